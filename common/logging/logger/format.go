@@ -13,14 +13,16 @@ type Format string
 const (
 	JSON Format = "json"
 	TEXT Format = "text"
+	// OTLP is a JSON format compatible with the OpenTelemetry log data model
+	OTLP Format = "otlp"
 )
 
-var allFormats = []Format{JSON, TEXT}
+var allFormats = []Format{JSON, TEXT, OTLP}
 
 func MapFormat(input string) (Format, error) {
 	var format = Format(input)
 	switch format {
-	case JSON, TEXT:
+	case JSON, TEXT, OTLP:
 		return format, nil
 	default:
 		return format, errors.New(fmt.Sprintf("Given log format: %s, doesn't match with any of %v", format, allFormats))
@@ -38,6 +40,8 @@ func (f Format) ToZapEncoder() (zapcore.Encoder, error) {
 		return zapcore.NewJSONEncoder(encoderConfig), nil
 	case TEXT:
 		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	case OTLP:
+		return newOTLPEncoder(encoderConfig), nil
 	default:
 		return nil, errors.New("unknown encoder")
 	}
@@ -28,6 +28,12 @@ func TestFormatMapping(t *testing.T) {
 			expected:    logger.JSON,
 			expectedErr: false,
 		},
+		{
+			name:        "otlp format",
+			input:       "otlp",
+			expected:    logger.OTLP,
+			expectedErr: false,
+		},
 		{
 			name:        "not existing format",
 			input:       "csv",
@@ -0,0 +1,613 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kyma-project/kyma/common/logging/tracing"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const contextNamespace = "context"
+
+var otlpBufferPool = buffer.NewPool()
+
+// otlpEncoder writes entries as log records in the OTLP/JSON encoding of the OpenTelemetry log data model. Tracing
+// metadata added by Logger.WithTracing becomes the traceId, spanId and flags fields, while all other fields, including
+// the caller, the parent span and fields added in the context namespace, are written directly as the attributes array
+// of KeyValue objects. Other namespaces, objects and arrays become kvlistValue and arrayValue values.
+// For more documentation see https://opentelemetry.io/docs/reference/specification/logs/data-model/ and
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type otlpEncoder struct {
+	config *zapcore.EncoderConfig
+	// buf holds the attributes encoded so far, separated by commas
+	buf *buffer.Buffer
+	// openNamespaces is the number of kvlistValue values opened by OpenNamespace, which are closed with the entry
+	openNamespaces int
+	// nested is the depth of objects and arrays being encoded. Their keys are never tracing metadata.
+	nested int
+	// inContext is true once the context namespace is opened. Its fields are written as top-level attributes.
+	inContext bool
+
+	traceID string
+	spanID  string
+	flags   *int
+}
+
+func newOTLPEncoder(encoderConfig zapcore.EncoderConfig) zapcore.Encoder {
+	encoderConfig.EncodeTime = unixNanoTimeEncoder
+	if encoderConfig.LineEnding == "" {
+		encoderConfig.LineEnding = zapcore.DefaultLineEnding
+	}
+	return &otlpEncoder{config: &encoderConfig, buf: otlpBufferPool.Get()}
+}
+
+// unixNanoTimeEncoder encodes time as a string, as 64-bit integers are represented in OTLP/JSON
+func unixNanoTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(strconv.FormatInt(t.UnixNano(), 10))
+}
+
+func (e *otlpEncoder) Clone() zapcore.Encoder {
+	return e.clone()
+}
+
+func (e *otlpEncoder) clone() *otlpEncoder {
+	cloned := *e
+	cloned.buf = otlpBufferPool.Get()
+	_, _ = cloned.buf.Write(e.buf.Bytes())
+	return &cloned
+}
+
+func (e *otlpEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := e.clone()
+	defer final.buf.Free()
+	for _, field := range fields {
+		field.AddTo(final)
+	}
+	final.closeOpenNamespaces()
+
+	out := otlpBufferPool.Get()
+	out.AppendString(`{"timeUnixNano":"`)
+	out.AppendInt(entry.Time.UnixNano())
+	out.AppendString(`","severityNumber":`)
+	out.AppendInt(int64(otlpSeverityNumber(entry.Level)))
+	out.AppendString(`,"severityText":"`)
+	out.AppendString(entry.Level.CapitalString())
+	out.AppendString(`","body":{"stringValue":`)
+	appendJSONString(out, entry.Message)
+	out.AppendByte('}')
+
+	attributes := e.entryAttributes(entry)
+	if attributes.buf.Len() > 0 || final.buf.Len() > 0 {
+		out.AppendString(`,"attributes":[`)
+		_, _ = out.Write(attributes.buf.Bytes())
+		if attributes.buf.Len() > 0 && final.buf.Len() > 0 {
+			out.AppendByte(',')
+		}
+		_, _ = out.Write(final.buf.Bytes())
+		out.AppendByte(']')
+	}
+	attributes.buf.Free()
+
+	if final.traceID != "" {
+		out.AppendString(`,"traceId":`)
+		appendJSONString(out, final.traceID)
+	}
+	if final.spanID != "" {
+		out.AppendString(`,"spanId":`)
+		appendJSONString(out, final.spanID)
+	}
+	if final.flags != nil {
+		out.AppendString(`,"flags":`)
+		out.AppendInt(int64(*final.flags))
+	}
+	out.AppendByte('}')
+	out.AppendString(e.config.LineEnding)
+	return out, nil
+}
+
+// entryAttributes encodes the logger name, caller, function and stack trace of the entry, if their keys are set
+func (e *otlpEncoder) entryAttributes(entry zapcore.Entry) *otlpEncoder {
+	enc := &otlpEncoder{config: e.config, buf: otlpBufferPool.Get(), nested: 1}
+	if entry.LoggerName != "" && e.config.NameKey != "" {
+		enc.addKey(e.config.NameKey)
+		encodeName := e.config.EncodeName
+		if encodeName == nil {
+			encodeName = zapcore.FullNameEncoder
+		}
+		enc.appendEncoded(func() { encodeName(entry.LoggerName, enc) }, entry.LoggerName)
+		enc.buf.AppendByte('}')
+	}
+	if entry.Caller.Defined {
+		if e.config.CallerKey != "" && e.config.EncodeCaller != nil {
+			enc.addKey(e.config.CallerKey)
+			enc.appendEncoded(func() { e.config.EncodeCaller(entry.Caller, enc) }, entry.Caller.String())
+			enc.buf.AppendByte('}')
+		}
+		if e.config.FunctionKey != "" {
+			enc.AddString(e.config.FunctionKey, entry.Caller.Function)
+		}
+	}
+	if entry.Stack != "" && e.config.StacktraceKey != "" {
+		enc.AddString(e.config.StacktraceKey, entry.Stack)
+	}
+	return enc
+}
+
+// appendEncoded runs a configured encoder of a value and appends the fallback string if it doesn't append anything
+func (e *otlpEncoder) appendEncoded(encode func(), fallback string) {
+	length := e.buf.Len()
+	encode()
+	if e.buf.Len() == length {
+		e.AppendString(fallback)
+	}
+}
+
+// addTracing records tracing metadata added by Logger.WithTracing. It returns false if the field is an attribute.
+func (e *otlpEncoder) addTracing(key, value string) bool {
+	if e.nested > 0 || e.openNamespaces > 0 || e.inContext {
+		return false
+	}
+	switch key {
+	case tracing.TRACE_KEY:
+		if value != tracing.UNKNOWN_VALUE {
+			e.traceID = value
+		}
+	case tracing.SPAN_KEY:
+		if value != tracing.UNKNOWN_VALUE {
+			e.spanID = value
+		}
+	case tracing.SAMPLED_KEY:
+		if sampled, err := strconv.ParseBool(value); err == nil {
+			flags := 0
+			if sampled {
+				flags = 1
+			}
+			e.flags = &flags
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// addElementSeparator separates the next KeyValue or AnyValue from the previous one
+func (e *otlpEncoder) addElementSeparator() {
+	last := e.buf.Len() - 1
+	if last < 0 {
+		return
+	}
+	switch e.buf.Bytes()[last] {
+	case '{', '[', ':', ',':
+		return
+	default:
+		e.buf.AppendByte(',')
+	}
+}
+
+// addKey opens a KeyValue object, which is closed after its value is appended
+func (e *otlpEncoder) addKey(key string) {
+	e.addElementSeparator()
+	e.buf.AppendString(`{"key":`)
+	appendJSONString(e.buf, key)
+	e.buf.AppendString(`,"value":`)
+}
+
+func (e *otlpEncoder) closeOpenNamespaces() {
+	for i := 0; i < e.openNamespaces; i++ {
+		e.buf.AppendString(`]}}}`)
+	}
+	e.openNamespaces = 0
+}
+
+func (e *otlpEncoder) OpenNamespace(key string) {
+	if key == contextNamespace && e.nested == 0 && e.openNamespaces == 0 && !e.inContext {
+		e.inContext = true
+		return
+	}
+	e.addKey(key)
+	e.buf.AppendString(`{"kvlistValue":{"values":[`)
+	e.openNamespaces++
+}
+
+func (e *otlpEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	e.addKey(key)
+	err := e.AppendArray(marshaler)
+	e.buf.AppendByte('}')
+	return err
+}
+
+func (e *otlpEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	e.addKey(key)
+	err := e.AppendObject(marshaler)
+	e.buf.AppendByte('}')
+	return err
+}
+
+func (e *otlpEncoder) AddBinary(key string, value []byte) {
+	e.addKey(key)
+	e.buf.AppendString(`{"bytesValue":"`)
+	e.buf.AppendString(base64.StdEncoding.EncodeToString(value))
+	e.buf.AppendString(`"}}`)
+}
+
+func (e *otlpEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+func (e *otlpEncoder) AddBool(key string, value bool) {
+	e.addKey(key)
+	e.AppendBool(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AddComplex128(key string, value complex128) {
+	e.addKey(key)
+	e.AppendComplex128(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AddComplex64(key string, value complex64) {
+	e.AddComplex128(key, complex128(value))
+}
+
+func (e *otlpEncoder) AddDuration(key string, value time.Duration) {
+	e.addKey(key)
+	e.AppendDuration(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AddFloat64(key string, value float64) {
+	e.addKey(key)
+	e.AppendFloat64(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AddFloat32(key string, value float32) {
+	e.AddFloat64(key, float64(value))
+}
+
+func (e *otlpEncoder) AddInt(key string, value int) {
+	e.AddInt64(key, int64(value))
+}
+
+func (e *otlpEncoder) AddInt64(key string, value int64) {
+	e.addKey(key)
+	e.AppendInt64(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AddInt32(key string, value int32) {
+	e.AddInt64(key, int64(value))
+}
+
+func (e *otlpEncoder) AddInt16(key string, value int16) {
+	e.AddInt64(key, int64(value))
+}
+
+func (e *otlpEncoder) AddInt8(key string, value int8) {
+	e.AddInt64(key, int64(value))
+}
+
+func (e *otlpEncoder) AddString(key, value string) {
+	if e.addTracing(key, value) {
+		return
+	}
+	e.addKey(key)
+	e.AppendString(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AddTime(key string, value time.Time) {
+	e.addKey(key)
+	e.AppendTime(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AddUint(key string, value uint) {
+	e.AddUint64(key, uint64(value))
+}
+
+func (e *otlpEncoder) AddUint64(key string, value uint64) {
+	e.addKey(key)
+	e.AppendUint64(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AddUint32(key string, value uint32) {
+	e.AddUint64(key, uint64(value))
+}
+
+func (e *otlpEncoder) AddUint16(key string, value uint16) {
+	e.AddUint64(key, uint64(value))
+}
+
+func (e *otlpEncoder) AddUint8(key string, value uint8) {
+	e.AddUint64(key, uint64(value))
+}
+
+func (e *otlpEncoder) AddUintptr(key string, value uintptr) {
+	e.AddUint64(key, uint64(value))
+}
+
+func (e *otlpEncoder) AddReflected(key string, value interface{}) error {
+	decoded, err := decodeReflected(value)
+	if err != nil {
+		return err
+	}
+	e.addKey(key)
+	e.appendDecoded(decoded)
+	e.buf.AppendByte('}')
+	return nil
+}
+
+func (e *otlpEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	e.addElementSeparator()
+	e.buf.AppendString(`{"arrayValue":{"values":[`)
+	e.nested++
+	err := marshaler.MarshalLogArray(e)
+	e.nested--
+	e.buf.AppendString(`]}}`)
+	return err
+}
+
+// AppendObject encodes the object as kvlistValue. Namespaces opened by the object are closed with it.
+func (e *otlpEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	e.addElementSeparator()
+	e.buf.AppendString(`{"kvlistValue":{"values":[`)
+	openNamespaces := e.openNamespaces
+	e.openNamespaces = 0
+	e.nested++
+	err := marshaler.MarshalLogObject(e)
+	e.nested--
+	e.closeOpenNamespaces()
+	e.openNamespaces = openNamespaces
+	e.buf.AppendString(`]}}`)
+	return err
+}
+
+func (e *otlpEncoder) AppendBool(value bool) {
+	e.addElementSeparator()
+	e.buf.AppendString(`{"boolValue":`)
+	e.buf.AppendBool(value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AppendByteString(value []byte) {
+	e.AppendString(string(value))
+}
+
+func (e *otlpEncoder) AppendComplex128(value complex128) {
+	e.AppendString(strconv.FormatComplex(value, 'g', -1, 64))
+}
+
+func (e *otlpEncoder) AppendComplex64(value complex64) {
+	e.AppendComplex128(complex128(value))
+}
+
+func (e *otlpEncoder) AppendDuration(value time.Duration) {
+	if e.config.EncodeDuration == nil {
+		e.AppendInt64(int64(value))
+		return
+	}
+	length := e.buf.Len()
+	e.config.EncodeDuration(value, e)
+	if e.buf.Len() == length {
+		e.AppendInt64(int64(value))
+	}
+}
+
+// AppendFloat64 encodes NaN and infinities as strings, as they can't be represented by JSON numbers
+func (e *otlpEncoder) AppendFloat64(value float64) {
+	e.addElementSeparator()
+	e.buf.AppendString(`{"doubleValue":`)
+	switch {
+	case math.IsNaN(value):
+		e.buf.AppendString(`"NaN"`)
+	case math.IsInf(value, 1):
+		e.buf.AppendString(`"Infinity"`)
+	case math.IsInf(value, -1):
+		e.buf.AppendString(`"-Infinity"`)
+	default:
+		e.buf.AppendFloat(value, 64)
+	}
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AppendFloat32(value float32) {
+	e.AppendFloat64(float64(value))
+}
+
+func (e *otlpEncoder) AppendInt(value int) {
+	e.AppendInt64(int64(value))
+}
+
+// AppendInt64 encodes the integer as a string, as 64-bit integers are represented in OTLP/JSON
+func (e *otlpEncoder) AppendInt64(value int64) {
+	e.addElementSeparator()
+	e.buf.AppendString(`{"intValue":"`)
+	e.buf.AppendInt(value)
+	e.buf.AppendString(`"}`)
+}
+
+func (e *otlpEncoder) AppendInt32(value int32) {
+	e.AppendInt64(int64(value))
+}
+
+func (e *otlpEncoder) AppendInt16(value int16) {
+	e.AppendInt64(int64(value))
+}
+
+func (e *otlpEncoder) AppendInt8(value int8) {
+	e.AppendInt64(int64(value))
+}
+
+func (e *otlpEncoder) AppendString(value string) {
+	e.addElementSeparator()
+	e.buf.AppendString(`{"stringValue":`)
+	appendJSONString(e.buf, value)
+	e.buf.AppendByte('}')
+}
+
+func (e *otlpEncoder) AppendTime(value time.Time) {
+	length := e.buf.Len()
+	e.config.EncodeTime(value, e)
+	if e.buf.Len() == length {
+		e.AppendInt64(value.UnixNano())
+	}
+}
+
+func (e *otlpEncoder) AppendUint(value uint) {
+	e.AppendUint64(uint64(value))
+}
+
+// AppendUint64 encodes integers which don't fit into the signed 64-bit OTLP integer as doubles
+func (e *otlpEncoder) AppendUint64(value uint64) {
+	if value > math.MaxInt64 {
+		e.AppendFloat64(float64(value))
+		return
+	}
+	e.AppendInt64(int64(value))
+}
+
+func (e *otlpEncoder) AppendUint32(value uint32) {
+	e.AppendUint64(uint64(value))
+}
+
+func (e *otlpEncoder) AppendUint16(value uint16) {
+	e.AppendUint64(uint64(value))
+}
+
+func (e *otlpEncoder) AppendUint8(value uint8) {
+	e.AppendUint64(uint64(value))
+}
+
+func (e *otlpEncoder) AppendUintptr(value uintptr) {
+	e.AppendUint64(uint64(value))
+}
+
+func (e *otlpEncoder) AppendReflected(value interface{}) error {
+	decoded, err := decodeReflected(value)
+	if err != nil {
+		return err
+	}
+	e.appendDecoded(decoded)
+	return nil
+}
+
+// decodeReflected converts a value of any type to JSON values, which are then mapped to AnyValue
+func decodeReflected(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// appendDecoded appends a decoded JSON value as AnyValue. Keys of objects are sorted, null is an empty AnyValue.
+func (e *otlpEncoder) appendDecoded(value interface{}) {
+	switch v := value.(type) {
+	case string:
+		e.AppendString(v)
+	case bool:
+		e.AppendBool(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			e.AppendInt64(i)
+		} else if f, err := v.Float64(); err == nil {
+			e.AppendFloat64(f)
+		} else {
+			e.AppendString(v.String())
+		}
+	case []interface{}:
+		e.addElementSeparator()
+		e.buf.AppendString(`{"arrayValue":{"values":[`)
+		for _, item := range v {
+			e.appendDecoded(item)
+		}
+		e.buf.AppendString(`]}}`)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		e.addElementSeparator()
+		e.buf.AppendString(`{"kvlistValue":{"values":[`)
+		for _, key := range keys {
+			e.addKey(key)
+			e.appendDecoded(v[key])
+			e.buf.AppendByte('}')
+		}
+		e.buf.AppendString(`]}}`)
+	default:
+		e.addElementSeparator()
+		e.buf.AppendString(`{}`)
+	}
+}
+
+// appendJSONString appends the string quoted and escaped for JSON. Invalid UTF-8 is replaced with U+FFFD.
+func appendJSONString(buf *buffer.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.AppendByte('"')
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			switch {
+			case b == '"' || b == '\\':
+				buf.AppendByte('\\')
+				buf.AppendByte(b)
+			case b == '\n':
+				buf.AppendString(`\n`)
+			case b == '\r':
+				buf.AppendString(`\r`)
+			case b == '\t':
+				buf.AppendString(`\t`)
+			case b < 0x20:
+				buf.AppendString(`\u00`)
+				buf.AppendByte(hex[b>>4])
+				buf.AppendByte(hex[b&0xf])
+			default:
+				buf.AppendByte(b)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.AppendString("\ufffd")
+		} else {
+			buf.AppendString(s[i : i+size])
+		}
+		i += size
+	}
+	buf.AppendByte('"')
+}
+
+// otlpSeverityNumber maps zap levels to the first severity number of the matching OpenTelemetry range
+func otlpSeverityNumber(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	default:
+		return 21
+	}
+}
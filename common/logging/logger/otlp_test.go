@@ -0,0 +1,297 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/logging/logger"
+	"github.com/kyma-project/kyma/common/logging/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type otlpLogEntry struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpAnyValue   `json:"body"`
+	TraceID        string         `json:"traceId"`
+	SpanID         string         `json:"spanId"`
+	Flags          *int           `json:"flags"`
+	Attributes     []otlpKeyValue `json:"attributes"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue"`
+	BoolValue   *bool    `json:"boolValue"`
+	IntValue    *string  `json:"intValue"`
+	DoubleValue *float64 `json:"doubleValue"`
+}
+
+// attributes returns attributes of the entry with values formatted as strings
+func (e otlpLogEntry) attributes() map[string]string {
+	attributes := map[string]string{}
+	for _, attribute := range e.Attributes {
+		value := attribute.Value
+		switch {
+		case value.StringValue != nil:
+			attributes[attribute.Key] = *value.StringValue
+		case value.BoolValue != nil:
+			attributes[attribute.Key] = strconv.FormatBool(*value.BoolValue)
+		case value.IntValue != nil:
+			attributes[attribute.Key] = "int:" + *value.IntValue
+		case value.DoubleValue != nil:
+			attributes[attribute.Key] = strconv.FormatFloat(*value.DoubleValue, 'f', -1, 64)
+		}
+	}
+	return attributes
+}
+
+func TestOTLPFormat(t *testing.T) {
+	t.Run("should log OpenTelemetry span in OTLP format", func(t *testing.T) {
+		// GIVEN
+		oldStdErr := os.Stderr
+		defer rollbackStderr(oldStdErr)
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		os.Stderr = w
+
+		log, err := logger.New(logger.OTLP, logger.DEBUG)
+		require.NoError(t, err)
+
+		ctx := fixOpenTelemetryContext(t, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+
+		// WHEN
+		log.WithTracing(ctx).With("key", "value", "count", 3, "enabled", true).Warn("example message")
+
+		// THEN
+		entry := readOTLPEntry(t, w, r)
+		assert.Equal(t, 13, entry.SeverityNumber)
+		assert.Equal(t, "WARN", entry.SeverityText)
+		require.NotNil(t, entry.Body.StringValue)
+		assert.Equal(t, "example message", *entry.Body.StringValue)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", entry.SpanID)
+		require.NotNil(t, entry.Flags)
+		assert.Equal(t, 1, *entry.Flags)
+		attributes := entry.attributes()
+		assert.Contains(t, attributes["caller"], "otlp_test.go")
+		delete(attributes, "caller")
+		assert.Equal(t, map[string]string{"key": "value", "count": "int:3", "enabled": "true"}, attributes)
+		assert.NotEmpty(t, entry.TimeUnixNano)
+	})
+
+	t.Run("should skip unknown trace in OTLP format", func(t *testing.T) {
+		// GIVEN
+		oldStdErr := os.Stderr
+		defer rollbackStderr(oldStdErr)
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		os.Stderr = w
+
+		log, err := logger.New(logger.OTLP, logger.DEBUG)
+		require.NoError(t, err)
+
+		// WHEN
+		log.WithTracing(context.TODO()).Error("example message")
+
+		// THEN
+		entry := readOTLPEntry(t, w, r)
+		assert.Equal(t, 17, entry.SeverityNumber)
+		assert.Empty(t, entry.TraceID)
+		assert.Empty(t, entry.SpanID)
+		assert.Nil(t, entry.Flags)
+	})
+}
+
+func TestOTLPFormatWithParentSpan(t *testing.T) {
+	// GIVEN
+	oldStdErr := os.Stderr
+	defer rollbackStderr(oldStdErr)
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stderr = w
+
+	log, err := logger.New(logger.OTLP, logger.DEBUG)
+	require.NoError(t, err)
+
+	var ctx context.Context
+	middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(tracing.TRACE_HEADER_KEY, "80f198ee56343ba864fe8b2a57d3eff7")
+	req.Header.Set(tracing.SPAN_HEADER_KEY, "e457b5a2e4d86bd1")
+	req.Header.Set(tracing.PARENT_SPAN_HEADER_KEY, "05e3ac9a4f6e3b90")
+	middleware.ServeHTTP(httptest.NewRecorder(), req)
+
+	// WHEN
+	log.WithTracing(ctx).Info("example message")
+
+	// THEN
+	entry := readOTLPEntry(t, w, r)
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", entry.TraceID)
+	assert.Equal(t, "e457b5a2e4d86bd1", entry.SpanID)
+	attributes := entry.attributes()
+	assert.Equal(t, "05e3ac9a4f6e3b90", attributes["parentspanid"])
+	assert.Contains(t, attributes["caller"], "otlp_test.go")
+}
+
+func TestOTLPEncoder(t *testing.T) {
+	t.Run("should encode nested fields as OTLP values", func(t *testing.T) {
+		// GIVEN
+		encoder, err := logger.OTLP.ToZapEncoder()
+		require.NoError(t, err)
+		encoder = encoder.Clone()
+		zap.String(tracing.TRACE_KEY, "4bf92f3577b34da6a3ce929d0e0e4736").AddTo(encoder)
+		zap.Namespace("context").AddTo(encoder)
+		zap.String("key", "with \"quotes\"").AddTo(encoder)
+
+		// WHEN
+		buf, err := encoder.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Unix(0, 42), Message: "msg"},
+			[]zapcore.Field{
+				zap.Strings("list", []string{"a", "b"}),
+				zap.Float64("ratio", 0.5),
+				zap.Binary("raw", []byte("raw")),
+				zap.Any("map", map[string]int{"b": 2, "a": 1}),
+				zap.Namespace("nested"),
+				zap.Bool("enabled", true),
+			})
+
+		// THEN
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"timeUnixNano": "42",
+			"severityNumber": 9,
+			"severityText": "INFO",
+			"body": {"stringValue": "msg"},
+			"traceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+			"attributes": [
+				{"key": "key", "value": {"stringValue": "with \"quotes\""}},
+				{"key": "list", "value": {"arrayValue": {"values": [{"stringValue": "a"}, {"stringValue": "b"}]}}},
+				{"key": "ratio", "value": {"doubleValue": 0.5}},
+				{"key": "raw", "value": {"bytesValue": "cmF3"}},
+				{"key": "map", "value": {"kvlistValue": {"values": [
+					{"key": "a", "value": {"intValue": "1"}},
+					{"key": "b", "value": {"intValue": "2"}}
+				]}}},
+				{"key": "nested", "value": {"kvlistValue": {"values": [
+					{"key": "enabled", "value": {"boolValue": true}}
+				]}}}
+			]
+		}`, buf.String())
+		assert.True(t, strings.HasSuffix(buf.String(), "}\n"))
+	})
+}
+
+// BenchmarkEncodeEntry compares the OTLP encoder with the JSON encoder for an entry with tracing metadata
+func BenchmarkEncodeEntry(b *testing.B) {
+	for _, format := range []logger.Format{logger.JSON, logger.OTLP} {
+		b.Run(string(format), func(b *testing.B) {
+			encoder, err := format.ToZapEncoder()
+			require.NoError(b, err)
+			encoder = encoder.Clone()
+			zap.String(tracing.TRACE_KEY, "4bf92f3577b34da6a3ce929d0e0e4736").AddTo(encoder)
+			zap.String(tracing.SPAN_KEY, "00f067aa0ba902b7").AddTo(encoder)
+			zap.String(tracing.SAMPLED_KEY, "true").AddTo(encoder)
+			zap.Namespace("context").AddTo(encoder)
+			entry := zapcore.Entry{
+				Level:   zapcore.InfoLevel,
+				Time:    time.Now(),
+				Message: "example message",
+				Caller:  zapcore.NewEntryCaller(0, "/src/logger/otlp_test.go", 42, true),
+			}
+			fields := []zapcore.Field{zap.String("key", "value"), zap.Int("count", 3), zap.Bool("enabled", true)}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				buf, err := encoder.EncodeEntry(entry, fields)
+				if err != nil {
+					b.Fatal(err)
+				}
+				buf.Free()
+			}
+		})
+	}
+}
+
+func TestLoggerWithOpenTelemetry(t *testing.T) {
+	t.Run("should log trace and span of OpenTelemetry span in json format", func(t *testing.T) {
+		// GIVEN
+		oldStdErr := os.Stderr
+		defer rollbackStderr(oldStdErr)
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		os.Stderr = w
+
+		log, err := logger.New(logger.JSON, logger.DEBUG)
+		require.NoError(t, err)
+
+		ctx := fixOpenTelemetryContext(t, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+
+		// WHEN
+		log.WithTracing(ctx).Info("example message")
+
+		// THEN
+		err = w.Close()
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, r)
+		require.NoError(t, err)
+
+		var entry map[string]interface{}
+		err = json.Unmarshal(buf.Bytes(), &entry)
+		require.NoError(t, err)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry["traceid"])
+		assert.Equal(t, "00f067aa0ba902b7", entry["spanid"])
+		assert.Equal(t, "true", entry["sampled"])
+	})
+}
+
+func fixOpenTelemetryContext(t *testing.T, traceID, spanID string) context.Context {
+	tid, err := trace.TraceIDFromHex(traceID)
+	require.NoError(t, err)
+	sid, err := trace.SpanIDFromHex(spanID)
+	require.NoError(t, err)
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+	})
+	return trace.ContextWithSpanContext(context.TODO(), spanContext)
+}
+
+func readOTLPEntry(t *testing.T, w, r *os.File) otlpLogEntry {
+	err := w.Close()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, r)
+	require.NoError(t, err)
+	require.NotEqual(t, 0, buf.Len())
+
+	var entry = otlpLogEntry{}
+	strictEncoder := json.NewDecoder(strings.NewReader(buf.String()))
+	strictEncoder.DisallowUnknownFields()
+	err = strictEncoder.Decode(&entry)
+	require.NoError(t, err)
+	return entry
+}
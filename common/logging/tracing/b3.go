@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	b3SamplingAccept = "1"
+	b3SamplingDeny   = "0"
+	b3SamplingDebug  = "d"
+	b3SpanIDLength   = 16
+)
+
+// b3 holds the fields of the compact B3 single header.
+// For more documentation see https://github.com/openzipkin/b3-propagation#single-header
+type b3 struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	sampling     string
+}
+
+func parseB3SingleHeader(value string) (b3, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return b3{}, errors.New("b3 header is empty")
+	}
+
+	parts := strings.Split(value, "-")
+	// the header may consist only of the sampling state
+	if len(parts) == 1 {
+		if !isB3SamplingState(parts[0]) {
+			return b3{}, errors.Errorf("invalid sampling state: %s", parts[0])
+		}
+		return b3{sampling: parts[0]}, nil
+	}
+	if len(parts) > 4 {
+		return b3{}, errors.New("invalid b3 format")
+	}
+
+	out := b3{traceID: parts[0], spanID: parts[1]}
+	if !isLowerHex(out.traceID) || (len(out.traceID) != traceIDLength && len(out.traceID) != traceIDLength/2) {
+		return b3{}, errors.Errorf("invalid trace id: %s", out.traceID)
+	}
+	if !isLowerHex(out.spanID) || len(out.spanID) != b3SpanIDLength {
+		return b3{}, errors.Errorf("invalid span id: %s", out.spanID)
+	}
+	if len(parts) > 2 {
+		if !isB3SamplingState(parts[2]) {
+			return b3{}, errors.Errorf("invalid sampling state: %s", parts[2])
+		}
+		out.sampling = parts[2]
+	}
+	if len(parts) > 3 {
+		if !isLowerHex(parts[3]) || len(parts[3]) != b3SpanIDLength {
+			return b3{}, errors.Errorf("invalid parent span id: %s", parts[3])
+		}
+		out.parentSpanID = parts[3]
+	}
+	return out, nil
+}
+
+func isB3SamplingState(value string) bool {
+	return value == b3SamplingAccept || value == b3SamplingDeny || value == b3SamplingDebug
+}
+
+// parseB3Sampled maps the sampling state used in B3 headers to the sampling decision. The second return value is
+// false if the decision is unknown.
+func parseB3Sampled(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case b3SamplingAccept, b3SamplingDebug, "true":
+		return true, true
+	case b3SamplingDeny, "false":
+		return false, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// IDGenerator creates trace and span IDs for requests which are not a part of any trace yet
+type IDGenerator interface {
+	// NewTraceID returns a new 128-bit trace ID encoded as 32 lowercase hex characters
+	NewTraceID() string
+	// NewSpanID returns a new 64-bit span ID encoded as 16 lowercase hex characters
+	NewSpanID() string
+}
+
+type randomIDGenerator struct{}
+
+// NewRandomIDGenerator returns IDGenerator which uses crypto/rand as a source of IDs
+func NewRandomIDGenerator() IDGenerator {
+	return randomIDGenerator{}
+}
+
+func (randomIDGenerator) NewTraceID() string {
+	return randomHex(traceIDLength / 2)
+}
+
+func (randomIDGenerator) NewSpanID() string {
+	return randomHex(parentIDLength / 2)
+}
+
+// randomHex returns n random bytes encoded as hex. IDs consisting only of zeros are invalid, so they are never
+// returned.
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+		if id := hex.EncodeToString(buf); !isZero(id) {
+			return id
+		}
+	}
+}
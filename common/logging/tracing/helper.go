@@ -1,22 +1,128 @@
 package tracing
 
-import "context"
+import (
+	"context"
+	"strconv"
+)
 
+// traceContextKey is the private key under which Trace is stored in the context
+type traceContextKey struct{}
+
+// Trace holds tracing metadata of a request. Use NewTrace to create it and WithTrace to store it in a context.
 type Trace struct {
-	traceID string
-	spanID  string
+	traceID      string
+	spanID       string
+	parentSpanID string
+	sampled      bool
+	sampledKnown bool
+	traceState   string
+}
+
+// NewTrace returns Trace with given trace and span IDs
+func NewTrace(traceID, spanID string) Trace {
+	return Trace{
+		traceID: traceID,
+		spanID:  spanID,
+	}
+}
+
+// TraceID returns the trace ID or an empty string if it is unknown
+func (t Trace) TraceID() string {
+	return t.traceID
+}
+
+// SpanID returns the span ID or an empty string if it is unknown
+func (t Trace) SpanID() string {
+	return t.spanID
 }
 
+// ParentSpanID returns the parent span ID or an empty string if it is unknown
+func (t Trace) ParentSpanID() string {
+	return t.parentSpanID
+}
+
+// Sampled returns the sampling decision. The second return value is false if the decision is unknown.
+func (t Trace) Sampled() (bool, bool) {
+	return t.sampled, t.sampledKnown
+}
+
+// TraceState returns the W3C tracestate or an empty string if it is unknown
+func (t Trace) TraceState() string {
+	return t.traceState
+}
+
+// WithParentSpanID returns a copy of Trace with given parent span ID
+func (t Trace) WithParentSpanID(parentSpanID string) Trace {
+	t.parentSpanID = parentSpanID
+	return t
+}
+
+// WithSampled returns a copy of Trace with given sampling decision
+func (t Trace) WithSampled(sampled bool) Trace {
+	t.sampled = sampled
+	t.sampledKnown = true
+	return t
+}
+
+// WithTraceState returns a copy of Trace with given W3C tracestate
+func (t Trace) WithTraceState(traceState string) Trace {
+	t.traceState = traceState
+	return t
+}
+
+// WithTrace returns a copy of the context which carries given Trace
+func WithTrace(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace)
+}
+
+// FromContext returns Trace of the OpenTelemetry span stored in the context. If there is none, it falls back to Trace
+// stored by WithTrace, e.g. by the tracing middleware. The span takes precedence, as it is started after the middleware
+// read the incoming request, so it is the current one in services which use both. The second return value is false if
+// neither of them is found.
+func FromContext(ctx context.Context) (Trace, bool) {
+	if trace, ok := fromOpenTelemetry(ctx); ok {
+		return trace, true
+	}
+	trace, ok := ctx.Value(traceContextKey{}).(Trace)
+	return trace, ok
+}
+
+// GetMetadata returns tracing metadata of the context as a map. Trace and span IDs are always present and set to
+// UNKNOWN_VALUE if missing, while parent span and sampling decision are returned only when known.
 func GetMetadata(ctx context.Context) map[string]string {
 	m := map[string]string{
 		TRACE_KEY: UNKNOWN_VALUE,
 		SPAN_KEY:  UNKNOWN_VALUE,
 	}
+	if trace, ok := FromContext(ctx); ok {
+		if trace.traceID != "" {
+			m[TRACE_KEY] = trace.traceID
+		}
+		if trace.spanID != "" {
+			m[SPAN_KEY] = trace.spanID
+		}
+		if trace.parentSpanID != "" {
+			m[PARENT_SPAN_KEY] = trace.parentSpanID
+		}
+		if trace.sampledKnown {
+			m[SAMPLED_KEY] = strconv.FormatBool(trace.sampled)
+		}
+		return m
+	}
+
+	// Deprecated: plain string keys are supported only for callers which put values into the context on their own.
+	// Use WithTrace instead.
 	if val, ok := ctx.Value(TRACE_KEY).(string); ok {
 		m[TRACE_KEY] = val
 	}
 	if val, ok := ctx.Value(SPAN_KEY).(string); ok {
 		m[SPAN_KEY] = val
 	}
+	if val, ok := ctx.Value(PARENT_SPAN_KEY).(string); ok {
+		m[PARENT_SPAN_KEY] = val
+	}
+	if val, ok := ctx.Value(SAMPLED_KEY).(string); ok {
+		m[SAMPLED_KEY] = val
+	}
 	return m
 }
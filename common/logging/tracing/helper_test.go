@@ -7,6 +7,7 @@ import (
 	"github.com/kyma-project/kyma/common/logging/tracing"
 
 	"github.com/bmizerany/assert"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 func TestGetMetadata(t *testing.T) {
@@ -22,6 +23,51 @@ func TestGetMetadata(t *testing.T) {
 		assert.Equal(t, "myspan", out[tracing.SPAN_KEY])
 	})
 
+	t.Run("context with parent span and sampling decision", func(t *testing.T) {
+		//GIVEN
+		ctx := fixContext(map[string]string{
+			tracing.TRACE_KEY:       "mytrace",
+			tracing.SPAN_KEY:        "myspan",
+			tracing.PARENT_SPAN_KEY: "myparent",
+			tracing.SAMPLED_KEY:     "true",
+		})
+
+		//WHEN
+		out := tracing.GetMetadata(ctx)
+
+		//THEN
+		assert.Equal(t, "myparent", out[tracing.PARENT_SPAN_KEY])
+		assert.Equal(t, "true", out[tracing.SAMPLED_KEY])
+	})
+
+	t.Run("context with trace", func(t *testing.T) {
+		//GIVEN
+		trace := tracing.NewTrace("mytrace", "myspan").WithParentSpanID("myparent").WithSampled(false)
+		ctx := tracing.WithTrace(context.TODO(), trace)
+
+		//WHEN
+		out := tracing.GetMetadata(ctx)
+
+		//THEN
+		assert.Equal(t, "mytrace", out[tracing.TRACE_KEY])
+		assert.Equal(t, "myspan", out[tracing.SPAN_KEY])
+		assert.Equal(t, "myparent", out[tracing.PARENT_SPAN_KEY])
+		assert.Equal(t, "false", out[tracing.SAMPLED_KEY])
+	})
+
+	t.Run("context with trace without IDs", func(t *testing.T) {
+		//GIVEN
+		ctx := tracing.WithTrace(context.TODO(), tracing.Trace{}.WithSampled(true))
+
+		//WHEN
+		out := tracing.GetMetadata(ctx)
+
+		//THEN
+		assert.Equal(t, tracing.UNKNOWN_VALUE, out[tracing.TRACE_KEY])
+		assert.Equal(t, tracing.UNKNOWN_VALUE, out[tracing.SPAN_KEY])
+		assert.Equal(t, "true", out[tracing.SAMPLED_KEY])
+	})
+
 	t.Run("context without values", func(t *testing.T) {
 		ctx := context.TODO()
 
@@ -31,8 +77,76 @@ func TestGetMetadata(t *testing.T) {
 		//THEN
 		assert.Equal(t, tracing.UNKNOWN_VALUE, out[tracing.TRACE_KEY])
 		assert.Equal(t, tracing.UNKNOWN_VALUE, out[tracing.SPAN_KEY])
+		assert.Equal(t, 2, len(out))
+	})
+
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("context with trace", func(t *testing.T) {
+		//GIVEN
+		trace := tracing.NewTrace("mytrace", "myspan").WithTraceState("rojo=00f067aa0ba902b7")
+		ctx := tracing.WithTrace(context.TODO(), trace)
+
+		//WHEN
+		out, ok := tracing.FromContext(ctx)
+
+		//THEN
+		assert.Equal(t, true, ok)
+		assert.Equal(t, trace, out)
+		assert.Equal(t, "rojo=00f067aa0ba902b7", out.TraceState())
+		_, known := out.Sampled()
+		assert.Equal(t, false, known)
+	})
+
+	t.Run("context with OpenTelemetry span", func(t *testing.T) {
+		//GIVEN
+		spanContext := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID: oteltrace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanID:  oteltrace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		})
+		ctx := oteltrace.ContextWithSpanContext(context.TODO(), spanContext)
+
+		//WHEN
+		out, ok := tracing.FromContext(ctx)
+
+		//THEN
+		assert.Equal(t, true, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", out.TraceID())
+		assert.Equal(t, "00f067aa0ba902b7", out.SpanID())
+		sampled, known := out.Sampled()
+		assert.Equal(t, true, known)
+		assert.Equal(t, false, sampled)
 	})
 
+	t.Run("context with trace and OpenTelemetry span", func(t *testing.T) {
+		//GIVEN
+		spanContext := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID: oteltrace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanID:  oteltrace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		})
+		ctx := tracing.WithTrace(context.TODO(), tracing.NewTrace("incomingtrace", "incomingspan"))
+		ctx = oteltrace.ContextWithSpanContext(ctx, spanContext)
+
+		//WHEN
+		out, ok := tracing.FromContext(ctx)
+
+		//THEN
+		assert.Equal(t, true, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", out.TraceID())
+		assert.Equal(t, "00f067aa0ba902b7", out.SpanID())
+	})
+
+	t.Run("context with plain string keys", func(t *testing.T) {
+		//GIVEN
+		ctx := fixContext(map[string]string{tracing.TRACE_KEY: "mytrace", tracing.SPAN_KEY: "myspan"})
+
+		//WHEN
+		_, ok := tracing.FromContext(ctx)
+
+		//THEN
+		assert.Equal(t, false, ok)
+	})
 }
 
 func fixContext(values map[string]string) context.Context {
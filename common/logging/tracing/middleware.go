@@ -1,42 +1,183 @@
 package tracing
 
 import (
-	"context"
 	"net/http"
 	"strings"
 )
 
 const (
-	SPAN_HEADER_KEY  = "X-B3-Spanid"
-	TRACE_HEADER_KEY = "X-B3-Traceid"
-	TRACE_KEY        = "traceid"
-	SPAN_KEY         = "spanid"
-	UNKNOWN_VALUE    = "unknown"
+	SPAN_HEADER_KEY        = "X-B3-Spanid"
+	TRACE_HEADER_KEY       = "X-B3-Traceid"
+	PARENT_SPAN_HEADER_KEY = "X-B3-Parentspanid"
+	SAMPLED_HEADER_KEY     = "X-B3-Sampled"
+	FLAGS_HEADER_KEY       = "X-B3-Flags"
+	B3_HEADER_KEY          = "B3"
+	TRACEPARENT_HEADER_KEY = "Traceparent"
+	TRACESTATE_HEADER_KEY  = "Tracestate"
+	TRACE_KEY              = "traceid"
+	SPAN_KEY               = "spanid"
+	PARENT_SPAN_KEY        = "parentspanid"
+	SAMPLED_KEY            = "sampled"
+	UNKNOWN_VALUE          = "unknown"
+
+	b3DebugFlag       = "1"
+	traceFlagsSampled = 0x01
 )
 
 type tracingMiddleware struct {
-	handler func(w http.ResponseWriter, r *http.Request)
+	handler       func(w http.ResponseWriter, r *http.Request)
+	generateSpans bool
+	idGenerator   IDGenerator
+}
+
+// MiddlewareOption configures the middleware created by NewTracingMiddleware
+type MiddlewareOption func(m *tracingMiddleware)
+
+// GenerateSpans makes the middleware create a new span for every request. The incoming span becomes its parent and
+// a new trace is started if the request doesn't belong to any. Trace and span IDs are echoed back in B3 response
+// headers.
+func GenerateSpans() MiddlewareOption {
+	return func(m *tracingMiddleware) {
+		m.generateSpans = true
+	}
 }
 
-func NewTracingMiddleware(handler func(w http.ResponseWriter, r *http.Request)) http.Handler {
-	return &tracingMiddleware{
-		handler: handler,
+// WithIDGenerator overrides the IDGenerator used by GenerateSpans, which by default is NewRandomIDGenerator
+func WithIDGenerator(generator IDGenerator) MiddlewareOption {
+	return func(m *tracingMiddleware) {
+		m.idGenerator = generator
 	}
 }
 
+func NewTracingMiddleware(handler func(w http.ResponseWriter, r *http.Request), opts ...MiddlewareOption) http.Handler {
+	m := &tracingMiddleware{
+		handler:     handler,
+		idGenerator: NewRandomIDGenerator(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
 func (m *tracingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	newCtx := addHeaderToCtx(r.Context(), r.Header, TRACE_HEADER_KEY, TRACE_KEY)
-	newCtx = addHeaderToCtx(newCtx, r.Header, SPAN_HEADER_KEY, SPAN_KEY)
+	trace, ok := traceFromHeaders(r.Header)
+	if m.generateSpans {
+		trace = m.newSpan(trace)
+		w.Header().Set(TRACE_HEADER_KEY, trace.traceID)
+		w.Header().Set(SPAN_HEADER_KEY, trace.spanID)
+		ok = true
+	}
+	if !ok {
+		m.handler(w, r)
+		return
+	}
 
-	m.handler(w, r.WithContext(newCtx))
+	m.handler(w, r.WithContext(WithTrace(r.Context(), trace)))
 	return
 }
 
-func addHeaderToCtx(ctx context.Context, headers http.Header, key string, desiredKey string) context.Context {
+// newSpan returns a child of the incoming span, or the root span of a new trace if there is no incoming one
+func (m *tracingMiddleware) newSpan(incoming Trace) Trace {
+	if incoming.traceID == "" {
+		out := NewTrace(m.idGenerator.NewTraceID(), m.idGenerator.NewSpanID())
+		out.sampled, out.sampledKnown = incoming.sampled, incoming.sampledKnown
+		return out
+	}
+
+	out := incoming
+	out.parentSpanID = incoming.spanID
+	out.spanID = m.idGenerator.NewSpanID()
+	return out
+}
+
+// traceFromHeaders reads tracing metadata from B3 headers and falls back to the W3C Trace Context. It returns false if
+// none of the headers was found.
+func traceFromHeaders(headers http.Header) (Trace, bool) {
+	trace := Trace{}
+	readB3Headers(headers, &trace)
+	readB3SingleHeader(headers, &trace)
+	readTraceContextHeaders(headers, &trace)
+	return trace, trace != Trace{}
+}
+
+func readB3Headers(headers http.Header, trace *Trace) {
+	trace.traceID = joinHeader(headers, TRACE_HEADER_KEY)
+	trace.spanID = joinHeader(headers, SPAN_HEADER_KEY)
+	trace.parentSpanID = joinHeader(headers, PARENT_SPAN_HEADER_KEY)
+	readB3Sampling(headers, trace)
+}
+
+func joinHeader(headers http.Header, key string) string {
 	header, ok := headers[key]
 	if !ok {
-		return ctx
+		return ""
+	}
+	return strings.Join(header, ";")
+}
+
+// readB3Sampling reads the sampling decision from X-B3-Sampled and X-B3-Flags headers. The debug flag implies that
+// the trace is sampled.
+func readB3Sampling(headers http.Header, trace *Trace) {
+	if flags := headers.Get(FLAGS_HEADER_KEY); flags == b3DebugFlag {
+		*trace = trace.WithSampled(true)
+		return
+	}
+	header, ok := headers[SAMPLED_HEADER_KEY]
+	if !ok || len(header) != 1 {
+		return
+	}
+	if sampled, ok := parseB3Sampled(header[0]); ok {
+		*trace = trace.WithSampled(sampled)
+	}
+}
+
+// readB3SingleHeader reads values from the b3 single header. Values found in the multi header variant take precedence.
+func readB3SingleHeader(headers http.Header, trace *Trace) {
+	header, ok := headers[B3_HEADER_KEY]
+	if !ok || len(header) != 1 {
+		return
+	}
+	single, err := parseB3SingleHeader(header[0])
+	if err != nil {
+		return
+	}
+
+	if single.traceID != "" && !trace.hasIDs() {
+		trace.traceID = single.traceID
+		trace.spanID = single.spanID
+		trace.parentSpanID = single.parentSpanID
+	}
+	if !trace.sampledKnown && single.sampling != "" {
+		sampled, _ := parseB3Sampled(single.sampling)
+		*trace = trace.WithSampled(sampled)
 	}
-	value := strings.Join(header, ";")
-	return context.WithValue(ctx, desiredKey, value)
+}
+
+// readTraceContextHeaders reads trace and span from the W3C Trace Context headers. B3 headers take precedence, so
+// the trace is left untouched if any of them was already found.
+func readTraceContextHeaders(headers http.Header, trace *Trace) {
+	if trace.hasIDs() {
+		return
+	}
+
+	header, ok := headers[TRACEPARENT_HEADER_KEY]
+	if !ok || len(header) != 1 {
+		return
+	}
+	parent, err := parseTraceParent(header[0])
+	if err != nil {
+		return
+	}
+
+	trace.traceID = parent.traceID
+	trace.spanID = parent.parentID
+	if !trace.sampledKnown {
+		*trace = trace.WithSampled(parent.flags&traceFlagsSampled != 0)
+	}
+	trace.traceState = strings.TrimSpace(strings.Join(headers[TRACESTATE_HEADER_KEY], ","))
+}
+
+func (t Trace) hasIDs() bool {
+	return t.traceID != "" || t.spanID != ""
 }
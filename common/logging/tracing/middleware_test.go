@@ -29,9 +29,10 @@ func TestMiddleware(t *testing.T) {
 		middleware.ServeHTTP(resp, r)
 
 		//THEN
-		ctx := outRequest.Context()
-		assert.Equal(t, "myspan", ctx.Value(tracing.SPAN_KEY))
-		assert.Equal(t, "mytrace", ctx.Value(tracing.TRACE_KEY))
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		assert.Equal(t, "myspan", trace.SpanID())
+		assert.Equal(t, "mytrace", trace.TraceID())
 	})
 
 	t.Run("wihtout trace and span should not change the context", func(t *testing.T) {
@@ -52,4 +53,388 @@ func TestMiddleware(t *testing.T) {
 		ctx := enhancedRequest.Context()
 		assert.Equal(t, ctx, r.Context())
 	})
+	t.Run("with traceparent in header, should put traceid and spanid to context", func(t *testing.T) {
+		//GIVEN
+		var outRequest *http.Request
+		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			outRequest = r
+		})
+		resp := httptest.NewRecorder()
+
+		r, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		r.Header.Add("tracestate", "rojo=00f067aa0ba902b7")
+		r.Header.Add("tracestate", "congo=t61rcWkgMzE")
+
+		//WHEN
+		middleware.ServeHTTP(resp, r)
+
+		//THEN
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID())
+		assert.Equal(t, "00f067aa0ba902b7", trace.SpanID())
+		assert.Equal(t, "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE", trace.TraceState())
+	})
+
+	t.Run("with both B3 and traceparent in header, should prefer B3", func(t *testing.T) {
+		//GIVEN
+		var outRequest *http.Request
+		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			outRequest = r
+		})
+		resp := httptest.NewRecorder()
+
+		r, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		r.Header[tracing.TRACE_HEADER_KEY] = []string{"mytrace"}
+		r.Header[tracing.SPAN_HEADER_KEY] = []string{"myspan"}
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		//WHEN
+		middleware.ServeHTTP(resp, r)
+
+		//THEN
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		assert.Equal(t, "myspan", trace.SpanID())
+		assert.Equal(t, "mytrace", trace.TraceID())
+	})
+
+	t.Run("with B3 sampling and parent span in header, should put them to context", func(t *testing.T) {
+		//GIVEN
+		var outRequest *http.Request
+		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			outRequest = r
+		})
+		resp := httptest.NewRecorder()
+
+		r, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		r.Header.Set("X-B3-TraceId", "mytrace")
+		r.Header.Set("X-B3-SpanId", "myspan")
+		r.Header.Set("X-B3-ParentSpanId", "myparent")
+		r.Header.Set("X-B3-Sampled", "0")
+
+		//WHEN
+		middleware.ServeHTTP(resp, r)
+
+		//THEN
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		assert.Equal(t, "mytrace", trace.TraceID())
+		assert.Equal(t, "myspan", trace.SpanID())
+		assert.Equal(t, "myparent", trace.ParentSpanID())
+		sampled, known := trace.Sampled()
+		assert.True(t, known)
+		assert.False(t, sampled)
+	})
+
+	t.Run("with B3 debug flag in header, should mark trace as sampled", func(t *testing.T) {
+		//GIVEN
+		var outRequest *http.Request
+		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			outRequest = r
+		})
+		resp := httptest.NewRecorder()
+
+		r, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		r.Header.Set("X-B3-Flags", "1")
+		r.Header.Set("X-B3-Sampled", "0")
+
+		//WHEN
+		middleware.ServeHTTP(resp, r)
+
+		//THEN
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		sampled, known := trace.Sampled()
+		assert.True(t, known)
+		assert.True(t, sampled)
+	})
+
+	t.Run("with traceparent in header, should put sampling flag to context", func(t *testing.T) {
+		//GIVEN
+		var outRequest *http.Request
+		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			outRequest = r
+		})
+		resp := httptest.NewRecorder()
+
+		r, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+		//WHEN
+		middleware.ServeHTTP(resp, r)
+
+		//THEN
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		sampled, known := trace.Sampled()
+		assert.True(t, known)
+		assert.False(t, sampled)
+	})
+}
+
+func TestMiddlewareB3SingleHeader(t *testing.T) {
+	testCases := []struct {
+		name           string
+		b3             string
+		expectedTrace  string
+		expectedSpan   string
+		expectedParent string
+		expectedSample string
+	}{
+		{
+			name:           "all fields",
+			b3:             "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90",
+			expectedTrace:  "80f198ee56343ba864fe8b2a57d3eff7",
+			expectedSpan:   "e457b5a2e4d86bd1",
+			expectedParent: "05e3ac9a4f6e3b90",
+			expectedSample: "true",
+		},
+		{
+			name:          "only trace and span with 64 bit trace id",
+			b3:            "64fe8b2a57d3eff7-e457b5a2e4d86bd1",
+			expectedTrace: "64fe8b2a57d3eff7",
+			expectedSpan:  "e457b5a2e4d86bd1",
+		},
+		{
+			name:           "debug sampling",
+			b3:             "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-d",
+			expectedTrace:  "80f198ee56343ba864fe8b2a57d3eff7",
+			expectedSpan:   "e457b5a2e4d86bd1",
+			expectedSample: "true",
+		},
+		{
+			name:           "only sampling state",
+			b3:             "0",
+			expectedSample: "false",
+		},
+		{
+			name: "invalid sampling state",
+			b3:   "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-x",
+		},
+		{
+			name: "invalid span id",
+			b3:   "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2",
+		},
+		{
+			name: "too many fields",
+			b3:   "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90-1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			//GIVEN
+			var outRequest *http.Request
+			middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				outRequest = r
+			})
+			resp := httptest.NewRecorder()
+
+			r, err := http.NewRequest(http.MethodGet, "", nil)
+			require.NoError(t, err)
+			r.Header.Set("b3", testCase.b3)
+
+			//WHEN
+			middleware.ServeHTTP(resp, r)
+
+			//THEN
+			trace, _ := tracing.FromContext(outRequest.Context())
+			assert.Equal(t, testCase.expectedTrace, trace.TraceID())
+			assert.Equal(t, testCase.expectedSpan, trace.SpanID())
+			assert.Equal(t, testCase.expectedParent, trace.ParentSpanID())
+			assert.Equal(t, testCase.expectedSample, tracing.GetMetadata(outRequest.Context())[tracing.SAMPLED_KEY])
+		})
+	}
+}
+
+func TestMiddlewareTraceParentValidation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		traceParent   []string
+		expectedTrace string
+		expectedSpan  string
+	}{
+		{
+			name:          "valid sampled",
+			traceParent:   []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			expectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpan:  "00f067aa0ba902b7",
+		},
+		{
+			name:          "valid not sampled",
+			traceParent:   []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+			expectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpan:  "00f067aa0ba902b7",
+		},
+		{
+			name:          "future version with additional fields",
+			traceParent:   []string{"cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future-will-be-like"},
+			expectedTrace: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedSpan:  "00f067aa0ba902b7",
+		},
+		{
+			name:        "forbidden version",
+			traceParent: []string{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		},
+		{
+			name:        "version 00 with additional fields",
+			traceParent: []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		},
+		{
+			name:        "uppercase trace-id",
+			traceParent: []string{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		},
+		{
+			name:        "all zero trace-id",
+			traceParent: []string{"00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		},
+		{
+			name:        "all zero parent-id",
+			traceParent: []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		},
+		{
+			name:        "invalid flags",
+			traceParent: []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x"},
+		},
+		{
+			name:        "wrong field lengths",
+			traceParent: []string{"00-4bf92f3577b34da6a3ce929d0e0e47366-0f067aa0ba902b7-01"},
+		},
+		{
+			name:        "too short",
+			traceParent: []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+		},
+		{
+			name: "multiple headers",
+			traceParent: []string{
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			//GIVEN
+			var outRequest *http.Request
+			middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				outRequest = r
+			})
+			resp := httptest.NewRecorder()
+
+			r, err := http.NewRequest(http.MethodGet, "", nil)
+			require.NoError(t, err)
+			r.Header[tracing.TRACEPARENT_HEADER_KEY] = testCase.traceParent
+			r.Header[tracing.TRACESTATE_HEADER_KEY] = []string{"rojo=00f067aa0ba902b7"}
+
+			//WHEN
+			middleware.ServeHTTP(resp, r)
+
+			//THEN
+			trace, _ := tracing.FromContext(outRequest.Context())
+			assert.Equal(t, testCase.expectedTrace, trace.TraceID())
+			assert.Equal(t, testCase.expectedSpan, trace.SpanID())
+			if testCase.expectedTrace == "" {
+				assert.Empty(t, trace.TraceState())
+			}
+		})
+	}
+}
+
+type fixedIDGenerator struct {
+	traceID string
+	spanIDs []string
+}
+
+func (g *fixedIDGenerator) NewTraceID() string {
+	return g.traceID
+}
+
+func (g *fixedIDGenerator) NewSpanID() string {
+	id := g.spanIDs[0]
+	g.spanIDs = g.spanIDs[1:]
+	return id
+}
+
+func TestMiddlewareSpanGeneration(t *testing.T) {
+	t.Run("without incoming trace, should start a new trace", func(t *testing.T) {
+		//GIVEN
+		var outRequest *http.Request
+		generator := &fixedIDGenerator{traceID: "newtrace", spanIDs: []string{"newspan"}}
+		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			outRequest = r
+		}, tracing.GenerateSpans(), tracing.WithIDGenerator(generator))
+		resp := httptest.NewRecorder()
+
+		r, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+
+		//WHEN
+		middleware.ServeHTTP(resp, r)
+
+		//THEN
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		assert.Equal(t, "newtrace", trace.TraceID())
+		assert.Equal(t, "newspan", trace.SpanID())
+		assert.Empty(t, trace.ParentSpanID())
+		assert.Equal(t, "newtrace", resp.Header().Get("X-B3-TraceId"))
+		assert.Equal(t, "newspan", resp.Header().Get("X-B3-SpanId"))
+	})
+
+	t.Run("with incoming trace, should create a child span", func(t *testing.T) {
+		//GIVEN
+		var outRequest *http.Request
+		generator := &fixedIDGenerator{traceID: "newtrace", spanIDs: []string{"childspan"}}
+		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			outRequest = r
+		}, tracing.GenerateSpans(), tracing.WithIDGenerator(generator))
+		resp := httptest.NewRecorder()
+
+		r, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		//WHEN
+		middleware.ServeHTTP(resp, r)
+
+		//THEN
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID())
+		assert.Equal(t, "childspan", trace.SpanID())
+		assert.Equal(t, "00f067aa0ba902b7", trace.ParentSpanID())
+		sampled, _ := trace.Sampled()
+		assert.True(t, sampled)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", resp.Header().Get("X-B3-TraceId"))
+		assert.Equal(t, "childspan", resp.Header().Get("X-B3-SpanId"))
+	})
+
+	t.Run("should generate valid random IDs by default", func(t *testing.T) {
+		//GIVEN
+		var outRequest *http.Request
+		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			outRequest = r
+		}, tracing.GenerateSpans())
+		resp := httptest.NewRecorder()
+
+		r, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+
+		//WHEN
+		middleware.ServeHTTP(resp, r)
+
+		//THEN
+		trace, ok := tracing.FromContext(outRequest.Context())
+		require.True(t, ok)
+		assert.Regexp(t, "^[0-9a-f]{32}$", trace.TraceID())
+		assert.Regexp(t, "^[0-9a-f]{16}$", trace.SpanID())
+	})
 }
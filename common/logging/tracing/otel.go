@@ -0,0 +1,23 @@
+package tracing
+
+import (
+	"context"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// fromOpenTelemetry returns Trace based on the OpenTelemetry span stored in the context. It returns false if there is
+// no valid span.
+func fromOpenTelemetry(ctx context.Context) (Trace, bool) {
+	spanContext := oteltrace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return Trace{}, false
+	}
+
+	trace := NewTrace(spanContext.TraceID().String(), spanContext.SpanID().String()).
+		WithSampled(spanContext.IsSampled())
+	if traceState := spanContext.TraceState().String(); traceState != "" {
+		trace = trace.WithTraceState(traceState)
+	}
+	return trace, true
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	traceParentVersion        = "00"
+	invalidTraceParentVersion = "ff"
+	traceIDLength             = 32
+	parentIDLength            = 16
+	traceParentLength         = 55
+)
+
+// traceParent holds the fields of the W3C Trace Context traceparent header.
+// For more documentation see https://www.w3.org/TR/trace-context/#traceparent-header
+type traceParent struct {
+	version  string
+	traceID  string
+	parentID string
+	flags    byte
+}
+
+func parseTraceParent(value string) (traceParent, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < traceParentLength {
+		return traceParent{}, errors.Errorf("traceparent is too short: %d characters", len(value))
+	}
+
+	version := value[:2]
+	if !isLowerHex(version) || version == invalidTraceParentVersion {
+		return traceParent{}, errors.Errorf("invalid traceparent version: %s", version)
+	}
+	// future versions may append fields, but they have to be separated by a dash
+	if version == traceParentVersion && len(value) != traceParentLength {
+		return traceParent{}, errors.Errorf("traceparent with version %s must have %d characters", version, traceParentLength)
+	}
+	if len(value) > traceParentLength && value[traceParentLength] != '-' {
+		return traceParent{}, errors.New("invalid traceparent format")
+	}
+
+	parts := strings.Split(value[:traceParentLength], "-")
+	if len(parts) != 4 || len(parts[1]) != traceIDLength || len(parts[2]) != parentIDLength || len(parts[3]) != 2 {
+		return traceParent{}, errors.New("invalid traceparent format")
+	}
+
+	traceID, parentID, flags := parts[1], parts[2], parts[3]
+	if !isLowerHex(traceID) || isZero(traceID) {
+		return traceParent{}, errors.Errorf("invalid trace-id: %s", traceID)
+	}
+	if !isLowerHex(parentID) || isZero(parentID) {
+		return traceParent{}, errors.Errorf("invalid parent-id: %s", parentID)
+	}
+	if !isLowerHex(flags) {
+		return traceParent{}, errors.Errorf("invalid trace-flags: %s", flags)
+	}
+	decodedFlags, err := hex.DecodeString(flags)
+	if err != nil {
+		return traceParent{}, errors.Wrap(err, "while decoding trace-flags")
+	}
+
+	return traceParent{
+		version:  version,
+		traceID:  traceID,
+		parentID: parentID,
+		flags:    decodedFlags[0],
+	}, nil
+}
+
+func isLowerHex(value string) bool {
+	for _, c := range value {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(value string) bool {
+	return strings.Trim(value, "0") == ""
+}
@@ -0,0 +1,89 @@
+package resilient
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	retry "github.com/avast/retry-go"
+)
+
+// BackoffType selects how the delay grows between consecutive retries
+type BackoffType string
+
+const (
+	// FixedBackoff keeps the same delay between all retries
+	FixedBackoff BackoffType = "fixed"
+	// ExponentialBackoff doubles the delay after every retry
+	ExponentialBackoff BackoffType = "exponential"
+)
+
+// DefaultBaseDelay is used if BaseDelay of Backoff is not set. It is the same as the default delay of retry-go.
+// Without WithBackoff, retries are delayed by the exponential backoff with this base delay.
+const DefaultBaseDelay = 100 * time.Millisecond
+
+// Backoff computes delays between retries. It supports a maximal delay and jitter.
+type Backoff struct {
+	// Type of the backoff, ExponentialBackoff by default
+	Type BackoffType
+	// BaseDelay before the first retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay. Zero means no limit.
+	MaxDelay time.Duration
+	// Jitter is the fraction of the delay, between 0 and 1, by which it is randomly increased or decreased, so that
+	// clients don't retry at the same moment
+	Jitter float64
+}
+
+// WithBackoff makes the client wait between retries according to given backoff. A longer Retry-After delay takes
+// precedence.
+func (c *WrappedHttpClient) WithBackoff(backoff Backoff) *WrappedHttpClient {
+	c.policy.WithBackoff(backoff)
+	return c
+}
+
+// Delay returns the delay before given retry, starting from 1
+func (b Backoff) Delay(retry int) time.Duration {
+	delay := b.BaseDelay
+	if delay <= 0 {
+		delay = DefaultBaseDelay
+	}
+	if b.Type != FixedBackoff && retry > 1 {
+		delay = toDuration(float64(delay) * math.Pow(2, float64(retry-1)))
+	}
+	if b.Jitter > 0 {
+		delay = toDuration(float64(delay) * (1 + b.Jitter*(2*rand.Float64()-1)))
+	}
+	if b.MaxDelay > 0 && delay > b.MaxDelay {
+		delay = b.MaxDelay
+	}
+	return delay
+}
+
+// toDuration converts nanoseconds to time.Duration without overflowing
+func toDuration(nanoseconds float64) time.Duration {
+	if nanoseconds >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(nanoseconds)
+}
+
+// countAttempts returns the maximal number of attempts set by retry options. retry-go doesn't expose its config, so
+// retry.Do is run without delays with an operation which always fails, and its calls are counted.
+func countAttempts(opts []retry.Option) uint {
+	var attempts uint
+	_ = retry.Do(func() error {
+		attempts++
+		return errCountAttempts
+	}, append(opts[:len(opts):len(opts)],
+		retry.Delay(0),
+		retry.DelayType(retry.FixedDelay),
+		retry.RetryIf(func(error) bool { return true }),
+		retry.OnRetry(func(uint, error) {}),
+		retry.LastErrorOnly(false),
+	)...)
+	return attempts
+}
+
+var errCountAttempts = errors.New("counting attempts")
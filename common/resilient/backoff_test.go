@@ -0,0 +1,43 @@
+package resilient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	for name, tc := range map[string]struct {
+		backoff  resilient.Backoff
+		retry    int
+		expected time.Duration
+	}{
+		"default base delay":      {backoff: resilient.Backoff{}, retry: 1, expected: resilient.DefaultBaseDelay},
+		"exponential first":       {backoff: resilient.Backoff{BaseDelay: time.Second}, retry: 1, expected: time.Second},
+		"exponential third":       {backoff: resilient.Backoff{BaseDelay: time.Second}, retry: 3, expected: 4 * time.Second},
+		"fixed third":             {backoff: resilient.Backoff{Type: resilient.FixedBackoff, BaseDelay: time.Second}, retry: 3, expected: time.Second},
+		"capped by max delay":     {backoff: resilient.Backoff{BaseDelay: time.Second, MaxDelay: 3 * time.Second}, retry: 3, expected: 3 * time.Second},
+		"capped without overflow": {backoff: resilient.Backoff{BaseDelay: time.Second, MaxDelay: time.Hour}, retry: 100, expected: time.Hour},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.backoff.Delay(tc.retry))
+		})
+	}
+
+	t.Run("should randomize delay with jitter", func(t *testing.T) {
+		// given
+		backoff := resilient.Backoff{Type: resilient.FixedBackoff, BaseDelay: time.Second, Jitter: 0.5}
+
+		for i := 0; i < 100; i++ {
+			// when
+			delay := backoff.Delay(1)
+
+			// then
+			assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+			assert.LessOrEqual(t, delay, 1500*time.Millisecond)
+		}
+	})
+}
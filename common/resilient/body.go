@@ -0,0 +1,102 @@
+package resilient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBufferedBodySize is the default limit of a request body without GetBody which is buffered in memory, so
+// that it can be sent again on retries
+const DefaultMaxBufferedBodySize = 1 << 20
+
+// ErrBodyNotRewindable is returned when a request needs a retry, but its body exceeds the buffering limit and has
+// already been consumed
+var ErrBodyNotRewindable = errors.New("request body can't be rewound for a retry")
+
+// WithMaxBufferedBodySize sets the limit of a request body which is buffered in memory if the request doesn't provide
+// GetBody. Requests with bigger bodies are sent only once. Bodies of requests which are neither retried nor hedged are
+// not buffered.
+func (c *WrappedHttpClient) WithMaxBufferedBodySize(size int64) *WrappedHttpClient {
+	c.maxBufferedBodySize = size
+	return c
+}
+
+// bodyRewinder provides a fresh request body for every attempt
+type bodyRewinder struct {
+	getBody   func() (io.ReadCloser, error)
+	body      io.ReadCloser
+	streaming bool
+	attempts  int
+}
+
+// newBodyRewinder prepares the body of the request to be sent multiple times. It uses GetBody if available, otherwise
+// it buffers up to maxSize bytes of the body if the body may be sent again. Bigger bodies and bodies of requests which
+// are sent only once are streamed untouched.
+func newBodyRewinder(req *http.Request, maxSize int64, resend bool) (*bodyRewinder, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		return &bodyRewinder{getBody: req.GetBody, body: req.Body}, nil
+	}
+	if !resend {
+		return &bodyRewinder{body: req.Body, streaming: true}, nil
+	}
+
+	buffered, err := io.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	if err != nil {
+		_ = req.Body.Close()
+		return nil, err
+	}
+	if int64(len(buffered)) > maxSize {
+		return &bodyRewinder{
+			body:      readCloser{Reader: io.MultiReader(bytes.NewReader(buffered), req.Body), Closer: req.Body},
+			streaming: true,
+		}, nil
+	}
+
+	if err := req.Body.Close(); err != nil {
+		return nil, err
+	}
+	getBody := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buffered)), nil
+	}
+	body, _ := getBody()
+	return &bodyRewinder{getBody: getBody, body: body}, nil
+}
+
+// rewindable returns false if the body can be sent only once
+func (r *bodyRewinder) rewindable() bool {
+	return r == nil || !r.streaming
+}
+
+// request returns a copy of the request with the body for the next attempt
+func (r *bodyRewinder) request(req *http.Request) (*http.Request, error) {
+	if r == nil {
+		return req, nil
+	}
+
+	body := r.body
+	if r.attempts > 0 {
+		if r.streaming {
+			return nil, ErrBodyNotRewindable
+		}
+		var err error
+		if body, err = r.getBody(); err != nil {
+			return nil, err
+		}
+	}
+	r.attempts++
+
+	out := req.Clone(req.Context())
+	out.Body = body
+	out.GetBody = r.getBody
+	return out, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
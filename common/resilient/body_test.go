@@ -0,0 +1,196 @@
+package resilient_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errConnection = errors.New("some connection error")
+
+type bodyReadingHttpClient struct {
+	successAfter int
+	bodies       []string
+}
+
+func (c *bodyReadingHttpClient) Do(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.bodies = append(c.bodies, string(body))
+	if len(c.bodies) == c.successAfter {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+	return nil, errConnection
+}
+
+type httpClientFunc func(req *http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// onlyReader hides other interfaces of the reader, so that http.NewRequest doesn't set GetBody
+type onlyReader struct {
+	io.Reader
+}
+
+func TestHttpClientBodyReplay(t *testing.T) {
+	t.Run("should buffer body without GetBody and send it on every attempt", func(t *testing.T) {
+		// given
+		mock := &bodyReadingHttpClient{successAfter: 3}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+
+		req, err := http.NewRequest(http.MethodPut, "http://example.com/", onlyReader{strings.NewReader("payload")})
+		require.NoError(t, err)
+
+		// when
+		resp, err := wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"payload", "payload", "payload"}, mock.bodies)
+	})
+
+	t.Run("should use GetBody to rewind body", func(t *testing.T) {
+		// given
+		mock := &bodyReadingHttpClient{successAfter: 2}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithMaxBufferedBodySize(0)
+
+		req, err := http.NewRequest(http.MethodPut, "http://example.com/", strings.NewReader("payload"))
+		require.NoError(t, err)
+
+		// when
+		resp, err := wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"payload", "payload"}, mock.bodies)
+	})
+
+	t.Run("should send streaming body once and fail when retry is needed", func(t *testing.T) {
+		// given
+		mock := &bodyReadingHttpClient{successAfter: 2}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithMaxBufferedBodySize(3)
+
+		req, err := http.NewRequest(http.MethodPut, "http://example.com/", onlyReader{strings.NewReader("payload")})
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, resilient.ErrBodyNotRewindable))
+		assert.True(t, errors.Is(err, errConnection))
+		assert.Equal(t, "request body can't be rewound for a retry: some connection error", err.Error())
+		assert.Equal(t, []string{"payload"}, mock.bodies)
+	})
+
+	t.Run("should send streaming body when no retry is needed", func(t *testing.T) {
+		// given
+		mock := &bodyReadingHttpClient{successAfter: 1}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithMaxBufferedBodySize(3)
+
+		// when
+		resp, err := wrapped.Post("http://example.com/", "text/plain", onlyReader{strings.NewReader("payload")})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"payload"}, mock.bodies)
+	})
+
+	t.Run("should return error of request which is not retried", func(t *testing.T) {
+		// given
+		mock := &bodyReadingHttpClient{successAfter: 2}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithMaxBufferedBodySize(3)
+
+		// when
+		_, err := wrapped.Post("http://example.com/", "text/plain", onlyReader{strings.NewReader("payload")})
+
+		// then
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, resilient.ErrBodyNotRewindable))
+		assert.Contains(t, err.Error(), errConnection.Error())
+		assert.Equal(t, []string{"payload"}, mock.bodies)
+	})
+
+	t.Run("should return error when retries are disabled", func(t *testing.T) {
+		// given
+		mock := &bodyReadingHttpClient{successAfter: 2}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(1)).WithMaxBufferedBodySize(3)
+		req, err := http.NewRequest(http.MethodPut, "http://example.com/", onlyReader{strings.NewReader("payload")})
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, resilient.ErrBodyNotRewindable))
+		assert.Contains(t, err.Error(), errConnection.Error())
+	})
+
+	t.Run("should return retryable response of request which is not retried", func(t *testing.T) {
+		// given
+		mock := &statusHttpClient{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableStatus(resilient.DefaultRetryableStatus).
+			WithMaxBufferedBodySize(3)
+
+		// when
+		resp, err := wrapped.Post("http://example.com/", "text/plain", onlyReader{strings.NewReader("payload")})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Len(t, mock.bodies, 1)
+		assert.False(t, mock.bodies[0].closed)
+	})
+
+	t.Run("should stream body of request which is not retried", func(t *testing.T) {
+		// given
+		reached := make(chan struct{})
+		mock := &bodyReadingHttpClient{successAfter: 1}
+		wrapped := resilient.WrapHttpClient(httpClientFunc(func(req *http.Request) (*http.Response, error) {
+			close(reached)
+			return mock.Do(req)
+		}), retry.Attempts(5))
+		reader, writer := io.Pipe()
+		go func() {
+			_, _ = io.WriteString(writer, "first ")
+			select {
+			case <-reached:
+				_, _ = io.WriteString(writer, "second")
+				_ = writer.Close()
+			case <-time.After(time.Second):
+				_ = writer.CloseWithError(errors.New("body was not streamed"))
+			}
+		}()
+
+		// when
+		resp, err := wrapped.Post("http://example.com/", "text/plain", reader)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"first second"}, mock.bodies)
+	})
+}
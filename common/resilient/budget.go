@@ -0,0 +1,158 @@
+package resilient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRetryBudgetExhausted is returned when a retry is refused, because the retry budget of the host is exhausted
+var ErrRetryBudgetExhausted = errors.New("retry budget is exhausted")
+
+// Default values used for zero fields of RetryBudgetConfig
+const (
+	DefaultRetryRatio          = 0.2
+	DefaultMinRetriesPerSecond = 1
+	DefaultBudgetWindow        = 10 * time.Second
+)
+
+// budgetBuckets is the number of buckets the window of a retry budget is split into
+const budgetBuckets = 10
+
+// RetryBudgetConfig configures RetryBudgets. Zero fields are set to default values.
+type RetryBudgetConfig struct {
+	// Ratio of retries to requests in the window, e.g. 0.2 allows one retry per five requests
+	Ratio float64
+	// MinRetriesPerSecond are allowed regardless of the number of requests
+	MinRetriesPerSecond float64
+	// Window in which requests and retries are counted
+	Window time.Duration
+}
+
+func (c RetryBudgetConfig) withDefaults() RetryBudgetConfig {
+	if c.Ratio <= 0 {
+		c.Ratio = DefaultRetryRatio
+	}
+	if c.MinRetriesPerSecond <= 0 {
+		c.MinRetriesPerSecond = DefaultMinRetriesPerSecond
+	}
+	if c.Window <= 0 {
+		c.Window = DefaultBudgetWindow
+	}
+	return c
+}
+
+// RetryBudgets limit retries to every host, so that they don't multiply the load during outages. They can be shared
+// by multiple clients with WithRetryBudget, so that retries of all of them are counted together.
+type RetryBudgets struct {
+	config RetryBudgetConfig
+
+	mu     sync.Mutex
+	byHost map[string]*retryBudget
+}
+
+// NewRetryBudgets returns new RetryBudgets which allow retries up to the configured ratio of recent requests to a host
+func NewRetryBudgets(config RetryBudgetConfig) *RetryBudgets {
+	return &RetryBudgets{
+		config: config.withDefaults(),
+		byHost: map[string]*retryBudget{},
+	}
+}
+
+// WithRetryBudget makes the client count requests and retries in given budgets and stop retrying once the budget of
+// the host is exhausted. In that case the last response with a retryable status code is returned or, if there is none,
+// ErrRetryBudgetExhausted wrapping the last error.
+func (c *WrappedHttpClient) WithRetryBudget(budgets *RetryBudgets) *WrappedHttpClient {
+	c.policy.WithRetryBudget(budgets, "")
+	return c
+}
+
+// retryBudget returns the budget of the policy key or, if it is empty, of given key and counts the operation in it. It
+// returns nil if there is no budget.
+func (p *Policy) retryBudget(key string) *retryBudget {
+	if p.retryBudgets == nil {
+		return nil
+	}
+	if p.budgetKey != "" {
+		key = p.budgetKey
+	}
+	budget := p.retryBudgets.get(key)
+	budget.request()
+	return budget
+}
+
+func (b *RetryBudgets) get(host string) *retryBudget {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	budget, ok := b.byHost[host]
+	if !ok {
+		budget = newRetryBudget(b.config)
+		b.byHost[host] = budget
+	}
+	return budget
+}
+
+// retryBudget counts requests and retries in a sliding window made of buckets
+type retryBudget struct {
+	config         RetryBudgetConfig
+	bucketDuration time.Duration
+
+	mu       sync.Mutex
+	requests [budgetBuckets]int
+	retries  [budgetBuckets]int
+	current  int
+	rotated  time.Time
+}
+
+func newRetryBudget(config RetryBudgetConfig) *retryBudget {
+	bucketDuration := config.Window / budgetBuckets
+	if bucketDuration <= 0 {
+		bucketDuration = 1
+	}
+	return &retryBudget{
+		config:         config,
+		bucketDuration: bucketDuration,
+		rotated:        time.Now(),
+	}
+}
+
+// request counts a new request
+func (b *retryBudget) request() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rotate(time.Now())
+	b.requests[b.current]++
+}
+
+// retry counts a retry and returns true if it is allowed by the budget
+func (b *retryBudget) retry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rotate(time.Now())
+
+	var requests, retries int
+	for i := range b.requests {
+		requests += b.requests[i]
+		retries += b.retries[i]
+	}
+	allowed := b.config.Ratio*float64(requests) + b.config.MinRetriesPerSecond*b.config.Window.Seconds()
+	if float64(retries+1) > allowed {
+		return false
+	}
+	b.retries[b.current]++
+	return true
+}
+
+// rotate moves to the bucket of given time, clearing buckets which fell out of the window
+func (b *retryBudget) rotate(now time.Time) {
+	elapsed := now.Sub(b.rotated) / b.bucketDuration
+	if elapsed <= 0 {
+		return
+	}
+	b.rotated = b.rotated.Add(elapsed * b.bucketDuration)
+	for i := 0; i < budgetBuckets && i < int(elapsed); i++ {
+		b.current = (b.current + 1) % budgetBuckets
+		b.requests[b.current] = 0
+		b.retries[b.current] = 0
+	}
+}
@@ -0,0 +1,113 @@
+package resilient_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientRetryBudget(t *testing.T) {
+	t.Run("should refuse retries once budget is exhausted", func(t *testing.T) {
+		// given
+		budgets := resilient.NewRetryBudgets(resilient.RetryBudgetConfig{
+			Ratio:               0.5,
+			MinRetriesPerSecond: 0.1,
+			Window:              10 * time.Second,
+		})
+		observer := &recordingObserver{}
+		mock := &errorHttpClient{err: errConnection}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryBudget(budgets).
+			WithObserver(observer)
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, resilient.ErrRetryBudgetExhausted))
+		assert.True(t, errors.Is(err, errConnection))
+		assert.Equal(t, "retry budget is exhausted: some connection error", err.Error())
+		assert.Equal(t, 2, mock.calls, "one retry should be allowed by 1 minimal retry and 0.5 of 1 request")
+		require.Len(t, observer.results, 1)
+		assert.True(t, observer.results[0].RetryRefused)
+		assert.True(t, observer.results[0].GaveUp)
+	})
+
+	t.Run("should share budget between clients of the same host", func(t *testing.T) {
+		// given
+		budgets := resilient.NewRetryBudgets(resilient.RetryBudgetConfig{
+			Ratio:               0.1,
+			MinRetriesPerSecond: 0.1,
+			Window:              10 * time.Second,
+		})
+		first := &mockHttpClient{successAfter: 2}
+		second := &mockHttpClient{successAfter: 2}
+		other := &mockHttpClient{successAfter: 2}
+
+		// when
+		_, firstErr := resilient.WrapHttpClient(first, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryBudget(budgets).Get("http://example.com/")
+		_, secondErr := resilient.WrapHttpClient(second, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryBudget(budgets).Get("http://example.com/")
+		_, otherErr := resilient.WrapHttpClient(other, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryBudget(budgets).Get("http://other.example.com/")
+
+		// then
+		assert.NoError(t, firstErr)
+		assert.Equal(t, 2, first.calls)
+		assert.True(t, errors.Is(secondErr, resilient.ErrRetryBudgetExhausted))
+		assert.Equal(t, 1, second.calls)
+		assert.NoError(t, otherErr)
+		assert.Equal(t, 2, other.calls)
+	})
+
+	t.Run("should return last retryable response when retry is refused", func(t *testing.T) {
+		// given
+		budgets := resilient.NewRetryBudgets(resilient.RetryBudgetConfig{MinRetriesPerSecond: 0.01})
+		mock := &statusHttpClient{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableStatus(resilient.DefaultRetryableStatus).
+			WithRetryBudget(budgets)
+
+		// when
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Len(t, mock.bodies, 1)
+		assert.False(t, mock.bodies[0].closed)
+	})
+
+	t.Run("should allow more retries as requests are made", func(t *testing.T) {
+		// given
+		budgets := resilient.NewRetryBudgets(resilient.RetryBudgetConfig{
+			Ratio:               0.5,
+			MinRetriesPerSecond: 0.01,
+			Window:              10 * time.Second,
+		})
+		for i := 0; i < 4; i++ {
+			succeeding := resilient.WrapHttpClient(&mockHttpClient{successAfter: 1}).WithRetryBudget(budgets)
+			_, err := succeeding.Get("http://example.com/")
+			require.NoError(t, err)
+		}
+		mock := &mockHttpClient{successAfter: 100}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(10)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryBudget(budgets)
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		assert.True(t, errors.Is(err, resilient.ErrRetryBudgetExhausted))
+		assert.Equal(t, 3, mock.calls, "two retries should be allowed by 0.5 of 5 requests")
+	})
+}
@@ -0,0 +1,151 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull is returned when a request is rejected, because all workers and the queue of its bulkhead are busy
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// DefaultMaxConcurrent is used if MaxConcurrent of BulkheadConfig is not set
+const DefaultMaxConcurrent = 10
+
+// BulkheadConfig configures Bulkhead. Zero MaxConcurrent is set to the default value.
+type BulkheadConfig struct {
+	// MaxConcurrent is the number of requests processed at the same time
+	MaxConcurrent int
+	// MaxQueue is the number of requests waiting for a worker. Zero means requests are rejected as soon as all workers
+	// are busy.
+	MaxQueue int
+	// MaxWait limits how long a request waits in the queue. Zero means it is limited only by the request context.
+	MaxWait time.Duration
+}
+
+func (c BulkheadConfig) withDefaults() BulkheadConfig {
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if c.MaxQueue < 0 {
+		c.MaxQueue = 0
+	}
+	return c
+}
+
+// BulkheadStats is a snapshot of the state of a bulkhead
+type BulkheadStats struct {
+	Name string
+	// Active is the number of requests being processed
+	Active int
+	// Queued is the number of requests waiting for a worker
+	Queued int
+	// Accepted is the total number of requests which got a worker
+	Accepted uint64
+	// Rejected is the total number of requests rejected because the bulkhead was full or the wait timed out
+	Rejected uint64
+}
+
+// Bulkhead isolates requests to a dependency with its own bounded pool of workers and queue, so that a slow dependency
+// can't exhaust resources shared with others. The same bulkhead can be used by multiple clients.
+type Bulkhead struct {
+	name    string
+	config  BulkheadConfig
+	admit   chan struct{}
+	workers chan struct{}
+
+	queued   int64
+	accepted uint64
+	rejected uint64
+}
+
+// NewBulkhead returns new Bulkhead. The name is used in errors and stats.
+func NewBulkhead(name string, config BulkheadConfig) *Bulkhead {
+	config = config.withDefaults()
+	return &Bulkhead{
+		name:    name,
+		config:  config,
+		admit:   make(chan struct{}, config.MaxConcurrent+config.MaxQueue),
+		workers: make(chan struct{}, config.MaxConcurrent),
+	}
+}
+
+// WithBulkhead makes the client process requests, including all their attempts, in given bulkhead. A request holds
+// its worker until the response body is closed.
+func (c *WrappedHttpClient) WithBulkhead(bulkhead *Bulkhead) *WrappedHttpClient {
+	c.bulkhead = bulkhead
+	return c
+}
+
+// Name returns the name of the bulkhead
+func (b *Bulkhead) Name() string {
+	return b.name
+}
+
+// Stats returns the current state of the bulkhead
+func (b *Bulkhead) Stats() BulkheadStats {
+	return BulkheadStats{
+		Name:     b.name,
+		Active:   len(b.workers),
+		Queued:   int(atomic.LoadInt64(&b.queued)),
+		Accepted: atomic.LoadUint64(&b.accepted),
+		Rejected: atomic.LoadUint64(&b.rejected),
+	}
+}
+
+// acquire waits for a worker and returns the function which releases it. It fails fast if the queue is full.
+func (b *Bulkhead) acquire(ctx context.Context) (func(), error) {
+	select {
+	case b.admit <- struct{}{}:
+	default:
+		atomic.AddUint64(&b.rejected, 1)
+		return nil, fmt.Errorf("%w: %s", ErrBulkheadFull, b.name)
+	}
+
+	var timeout <-chan time.Time
+	if b.config.MaxWait > 0 {
+		timer := time.NewTimer(b.config.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	atomic.AddInt64(&b.queued, 1)
+	defer atomic.AddInt64(&b.queued, -1)
+	select {
+	case b.workers <- struct{}{}:
+		atomic.AddUint64(&b.accepted, 1)
+		return func() {
+			<-b.workers
+			<-b.admit
+		}, nil
+	case <-timeout:
+		<-b.admit
+		atomic.AddUint64(&b.rejected, 1)
+		return nil, fmt.Errorf("%w: %s: waited %s", ErrBulkheadFull, b.name, b.config.MaxWait)
+	case <-ctx.Done():
+		<-b.admit
+		return nil, ctx.Err()
+	}
+}
+
+// isolate runs the request with all its attempts in the bulkhead of the client
+func (c *WrappedHttpClient) isolate(req *http.Request, rewinder *bodyRewinder) (*http.Response, error) {
+	if c.bulkhead == nil {
+		return c.retry(req, rewinder)
+	}
+
+	release, err := c.bulkhead.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.retry(req, rewinder)
+	if err != nil || resp == nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+	resp.Body = onClose(resp.Body, release)
+	return resp, nil
+}
@@ -0,0 +1,134 @@
+package resilient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientBulkhead(t *testing.T) {
+	t.Run("should reject requests when bulkhead is full", func(t *testing.T) {
+		// given
+		bulkhead := resilient.NewBulkhead("dependency", resilient.BulkheadConfig{MaxConcurrent: 1})
+		wrapped := resilient.WrapHttpClient(&countingHttpClient{}).WithBulkhead(bulkhead)
+		first, err := wrapped.Get("http://example.com/")
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Get("http://example.com/")
+
+		// then
+		assert.True(t, errors.Is(err, resilient.ErrBulkheadFull))
+		assert.Contains(t, err.Error(), "dependency")
+		assert.Equal(t, resilient.BulkheadStats{Name: "dependency", Active: 1, Accepted: 1, Rejected: 1}, bulkhead.Stats())
+
+		// when
+		require.NoError(t, first.Body.Close())
+		_, err = wrapped.Get("http://example.com/")
+
+		// then
+		assert.NoError(t, err)
+	})
+
+	t.Run("should queue requests until a worker is released", func(t *testing.T) {
+		// given
+		bulkhead := resilient.NewBulkhead("dependency", resilient.BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1})
+		wrapped := resilient.WrapHttpClient(&countingHttpClient{}).WithBulkhead(bulkhead)
+		first, err := wrapped.Get("http://example.com/")
+		require.NoError(t, err)
+
+		queued := make(chan error, 1)
+		go func() {
+			resp, err := wrapped.Get("http://example.com/")
+			if err == nil {
+				err = resp.Body.Close()
+			}
+			queued <- err
+		}()
+		require.Eventually(t, func() bool {
+			return bulkhead.Stats().Queued == 1
+		}, time.Second, time.Millisecond)
+
+		// when
+		_, rejectedErr := wrapped.Get("http://example.com/")
+		require.NoError(t, first.Body.Close())
+
+		// then
+		assert.True(t, errors.Is(rejectedErr, resilient.ErrBulkheadFull))
+		assert.NoError(t, <-queued)
+		stats := bulkhead.Stats()
+		assert.Equal(t, 0, stats.Active)
+		assert.Equal(t, uint64(2), stats.Accepted)
+		assert.Equal(t, uint64(1), stats.Rejected)
+	})
+
+	t.Run("should reject requests waiting longer than allowed", func(t *testing.T) {
+		// given
+		bulkhead := resilient.NewBulkhead("dependency", resilient.BulkheadConfig{
+			MaxConcurrent: 1,
+			MaxQueue:      1,
+			MaxWait:       20 * time.Millisecond,
+		})
+		wrapped := resilient.WrapHttpClient(&countingHttpClient{}).WithBulkhead(bulkhead)
+		_, err := wrapped.Get("http://example.com/")
+		require.NoError(t, err)
+
+		// when
+		start := time.Now()
+		_, err = wrapped.Get("http://example.com/")
+
+		// then
+		assert.True(t, errors.Is(err, resilient.ErrBulkheadFull))
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+		assert.Equal(t, 0, bulkhead.Stats().Queued)
+	})
+
+	t.Run("should isolate dependencies", func(t *testing.T) {
+		// given
+		slow := resilient.NewBulkhead("slow", resilient.BulkheadConfig{MaxConcurrent: 1})
+		fast := resilient.NewBulkhead("fast", resilient.BulkheadConfig{MaxConcurrent: 1})
+		slowClient := resilient.WrapHttpClient(&countingHttpClient{}).WithBulkhead(slow)
+		fastClient := resilient.WrapHttpClient(&countingHttpClient{}).WithBulkhead(fast)
+		_, err := slowClient.Get("http://slow.example.com/")
+		require.NoError(t, err)
+
+		// when
+		_, slowErr := slowClient.Get("http://slow.example.com/")
+		resp, fastErr := fastClient.Get("http://fast.example.com/")
+
+		// then
+		assert.True(t, errors.Is(slowErr, resilient.ErrBulkheadFull))
+		require.NoError(t, fastErr)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should release worker when request is cancelled during retry delay", func(t *testing.T) {
+		// given
+		bulkhead := resilient.NewBulkhead("dependency", resilient.BulkheadConfig{MaxConcurrent: 1})
+		wrapped := resilient.WrapHttpClient(&countingHttpClient{successAfter: 2}, retry.Attempts(5)).
+			WithBackoff(fixedBackoff(2 * time.Second)).
+			WithBulkhead(bulkhead)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := wrapped.GetWithContext(ctx, "http://example.com/")
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+		// when
+		require.Eventually(t, func() bool {
+			return bulkhead.Stats().Active == 0
+		}, 500*time.Millisecond, time.Millisecond)
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,275 @@
+package resilient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request is rejected by an open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is a state of CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through and counts failures
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests until the cool-down passes
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial requests through to check if the dependency recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// Default values used for zero fields of CircuitBreakerConfig
+const (
+	DefaultFailureRatio     = 0.5
+	DefaultMinRequests      = 10
+	DefaultWindow           = 10 * time.Second
+	DefaultCoolDown         = 30 * time.Second
+	DefaultHalfOpenRequests = 1
+)
+
+// CircuitBreakerConfig configures CircuitBreaker. Zero fields are set to default values.
+type CircuitBreakerConfig struct {
+	// FailureRatio of requests in the window which opens the circuit
+	FailureRatio float64
+	// MinRequests in the window before FailureRatio is evaluated
+	MinRequests int
+	// Window in which requests are counted
+	Window time.Duration
+	// CoolDown after which an open circuit lets trial requests through
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of concurrent trial requests in the half-open state
+	HalfOpenRequests int
+	// OnStateChange is called with the name of the circuit breaker on every state change. It must not block.
+	OnStateChange func(name string, from, to CircuitState)
+}
+
+func (c CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	if c.FailureRatio <= 0 {
+		c.FailureRatio = DefaultFailureRatio
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = DefaultMinRequests
+	}
+	if c.Window <= 0 {
+		c.Window = DefaultWindow
+	}
+	if c.CoolDown <= 0 {
+		c.CoolDown = DefaultCoolDown
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = DefaultHalfOpenRequests
+	}
+	return c
+}
+
+// CircuitBreaker stops sending requests to a failing dependency. It opens when the ratio of failed requests in the
+// window exceeds the configured threshold, rejects requests during the cool-down and then closes again when trial
+// requests succeed.
+type CircuitBreaker struct {
+	name   string
+	config CircuitBreakerConfig
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  uint64
+	windowStart time.Time
+	openedAt    time.Time
+	requests    int
+	failures    int
+	trials      int
+}
+
+// NewCircuitBreaker returns new CircuitBreaker in the closed state. The name is passed to OnStateChange.
+func NewCircuitBreaker(name string, config CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:        name,
+		config:      config.withDefaults(),
+		windowStart: time.Now(),
+	}
+}
+
+// State returns the current state of the circuit breaker
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.currentState(time.Now())
+}
+
+// Allow returns ErrCircuitOpen if the request must not be sent. Otherwise it returns a function which has to be called
+// with the result of the request.
+func (cb *CircuitBreaker) Allow() (func(success bool), error) {
+	generation, err := cb.allow()
+	if err != nil {
+		return nil, err
+	}
+	return func(success bool) {
+		cb.record(generation, success)
+	}, nil
+}
+
+// allow returns the generation of the state in which the request was allowed
+func (cb *CircuitBreaker) allow() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.currentState(time.Now()) {
+	case CircuitOpen:
+		return 0, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if cb.trials >= cb.config.HalfOpenRequests {
+			return 0, ErrCircuitOpen
+		}
+		cb.trials++
+	}
+	return cb.generation, nil
+}
+
+// release gives back an allowed request without counting its result, e.g. when it was cancelled by the caller
+func (cb *CircuitBreaker) release(generation uint64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if generation == cb.generation && cb.state == CircuitHalfOpen && cb.trials > 0 {
+		cb.trials--
+	}
+}
+
+func (cb *CircuitBreaker) record(generation uint64, success bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := time.Now()
+	state := cb.currentState(now)
+	// results of requests allowed before the last state change are outdated
+	if generation != cb.generation {
+		return
+	}
+
+	switch state {
+	case CircuitHalfOpen:
+		if success {
+			cb.setState(CircuitClosed, now)
+		} else {
+			cb.setState(CircuitOpen, now)
+		}
+	case CircuitClosed:
+		cb.requests++
+		if !success {
+			cb.failures++
+		}
+		if cb.requests >= cb.config.MinRequests && float64(cb.failures)/float64(cb.requests) >= cb.config.FailureRatio {
+			cb.setState(CircuitOpen, now)
+		}
+	}
+}
+
+// currentState moves the circuit breaker to the half-open state after the cool-down and starts a new window when the
+// previous one passed
+func (cb *CircuitBreaker) currentState(now time.Time) CircuitState {
+	switch cb.state {
+	case CircuitOpen:
+		if now.Sub(cb.openedAt) >= cb.config.CoolDown {
+			cb.setState(CircuitHalfOpen, now)
+		}
+	case CircuitClosed:
+		if now.Sub(cb.windowStart) >= cb.config.Window {
+			cb.resetWindow(now)
+		}
+	}
+	return cb.state
+}
+
+func (cb *CircuitBreaker) setState(state CircuitState, now time.Time) {
+	if cb.state == state {
+		return
+	}
+	from := cb.state
+	cb.state = state
+	cb.generation++
+	cb.trials = 0
+	cb.resetWindow(now)
+	if state == CircuitOpen {
+		cb.openedAt = now
+	}
+	if cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(cb.name, from, state)
+	}
+}
+
+func (cb *CircuitBreaker) resetWindow(now time.Time) {
+	cb.windowStart = now
+	cb.requests = 0
+	cb.failures = 0
+}
+
+// circuitBreakers holds a circuit breaker per host
+type circuitBreakers struct {
+	config CircuitBreakerConfig
+
+	mu     sync.Mutex
+	byHost map[string]*CircuitBreaker
+}
+
+func newCircuitBreakers(config CircuitBreakerConfig) *circuitBreakers {
+	return &circuitBreakers{
+		config: config,
+		byHost: map[string]*CircuitBreaker{},
+	}
+}
+
+func (b *circuitBreakers) get(host string) *CircuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cb, ok := b.byHost[host]
+	if !ok {
+		cb = NewCircuitBreaker(host, b.config)
+		b.byHost[host] = cb
+	}
+	return cb
+}
+
+// WithCircuitBreaker makes the client use a separate CircuitBreaker for every host. Failed attempts and attempts
+// which received a retryable status code count as failures. Requests rejected by an open circuit are not retried and
+// return an error wrapping ErrCircuitOpen.
+func (c *WrappedHttpClient) WithCircuitBreaker(config CircuitBreakerConfig) *WrappedHttpClient {
+	c.policy.circuitBreakers = newCircuitBreakers(config)
+	return c
+}
+
+// CircuitState returns the state of the circuit breaker for given host. It returns CircuitClosed if circuit breakers
+// are not enabled or there were no requests to the host yet.
+func (c *WrappedHttpClient) CircuitState(host string) CircuitState {
+	if cb := c.policy.circuitBreakerFor(host); cb != nil {
+		return cb.State()
+	}
+	return CircuitClosed
+}
+
+// circuitBreakerFor returns the circuit breaker of the policy or, if there is none, the circuit breaker of given key.
+// It returns nil if circuit breakers are not enabled.
+func (p *Policy) circuitBreakerFor(key string) *CircuitBreaker {
+	switch {
+	case p.circuitBreaker != nil:
+		return p.circuitBreaker
+	case p.circuitBreakers != nil:
+		return p.circuitBreakers.get(key)
+	default:
+		return nil
+	}
+}
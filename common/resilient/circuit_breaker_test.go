@@ -0,0 +1,150 @@
+package resilient_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stateChange struct {
+	name     string
+	from, to resilient.CircuitState
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("should open, half-open and close again", func(t *testing.T) {
+		// given
+		var changes []stateChange
+		cb := resilient.NewCircuitBreaker("dependency", resilient.CircuitBreakerConfig{
+			FailureRatio: 0.5,
+			MinRequests:  2,
+			CoolDown:     50 * time.Millisecond,
+			OnStateChange: func(name string, from, to resilient.CircuitState) {
+				changes = append(changes, stateChange{name: name, from: from, to: to})
+			},
+		})
+
+		// when
+		done, err := cb.Allow()
+		require.NoError(t, err)
+		done(true)
+		done, err = cb.Allow()
+		require.NoError(t, err)
+		done(false)
+
+		// then
+		assert.Equal(t, resilient.CircuitOpen, cb.State())
+		_, err = cb.Allow()
+		assert.Equal(t, resilient.ErrCircuitOpen, err)
+
+		// when
+		time.Sleep(50 * time.Millisecond)
+
+		// then
+		assert.Equal(t, resilient.CircuitHalfOpen, cb.State())
+		trial, err := cb.Allow()
+		require.NoError(t, err)
+		_, err = cb.Allow()
+		assert.Equal(t, resilient.ErrCircuitOpen, err)
+
+		// when
+		trial(true)
+
+		// then
+		assert.Equal(t, resilient.CircuitClosed, cb.State())
+		assert.Equal(t, []stateChange{
+			{name: "dependency", from: resilient.CircuitClosed, to: resilient.CircuitOpen},
+			{name: "dependency", from: resilient.CircuitOpen, to: resilient.CircuitHalfOpen},
+			{name: "dependency", from: resilient.CircuitHalfOpen, to: resilient.CircuitClosed},
+		}, changes)
+	})
+
+	t.Run("should open again when trial request fails", func(t *testing.T) {
+		// given
+		cb := resilient.NewCircuitBreaker("dependency", resilient.CircuitBreakerConfig{
+			MinRequests: 1,
+			CoolDown:    10 * time.Millisecond,
+		})
+		done, err := cb.Allow()
+		require.NoError(t, err)
+		done(false)
+		time.Sleep(10 * time.Millisecond)
+
+		// when
+		trial, err := cb.Allow()
+		require.NoError(t, err)
+		trial(false)
+
+		// then
+		assert.Equal(t, resilient.CircuitOpen, cb.State())
+	})
+
+	t.Run("should not count failures from previous window", func(t *testing.T) {
+		// given
+		cb := resilient.NewCircuitBreaker("dependency", resilient.CircuitBreakerConfig{
+			MinRequests: 2,
+			Window:      20 * time.Millisecond,
+		})
+		done, err := cb.Allow()
+		require.NoError(t, err)
+		done(false)
+		time.Sleep(20 * time.Millisecond)
+
+		// when
+		done, err = cb.Allow()
+		require.NoError(t, err)
+		done(false)
+
+		// then
+		assert.Equal(t, resilient.CircuitClosed, cb.State())
+	})
+}
+
+func TestHttpClientCircuitBreaker(t *testing.T) {
+	t.Run("should stop retrying when circuit opens", func(t *testing.T) {
+		// given
+		mock := &mockHttpClient{successAfter: 100}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithCircuitBreaker(resilient.CircuitBreakerConfig{MinRequests: 3, FailureRatio: 1, CoolDown: time.Minute})
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		assert.True(t, errors.Is(err, resilient.ErrCircuitOpen))
+		assert.Equal(t, 3, mock.calls)
+		assert.Equal(t, resilient.CircuitOpen, wrapped.CircuitState("example.com"))
+
+		// when
+		_, err = wrapped.Get("http://example.com/")
+
+		// then
+		assert.True(t, errors.Is(err, resilient.ErrCircuitOpen))
+		assert.Equal(t, 3, mock.calls)
+		assert.Equal(t, resilient.CircuitClosed, wrapped.CircuitState("other.example.com"))
+	})
+
+	t.Run("should count retryable status codes as failures", func(t *testing.T) {
+		// given
+		mock := &statusHttpClient{statuses: []int{http.StatusServiceUnavailable}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(1)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithCircuitBreaker(resilient.CircuitBreakerConfig{MinRequests: 2, CoolDown: time.Minute})
+
+		// when
+		for i := 0; i < 2; i++ {
+			resp, err := wrapped.Get("http://example.com/")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		}
+
+		// then
+		assert.Equal(t, resilient.CircuitOpen, wrapped.CircuitState("example.com"))
+	})
+}
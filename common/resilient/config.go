@@ -0,0 +1,231 @@
+package resilient
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	retry "github.com/avast/retry-go"
+	"gopkg.in/yaml.v2"
+)
+
+// Config declares the resilience of WrappedHttpClient. It can be loaded from YAML with LoadConfigFromYAML or from
+// environment variables with LoadConfigFromEnv. Zero fields keep default values.
+type Config struct {
+	// Attempts is the maximal number of attempts of a request, including the first one
+	Attempts uint `yaml:"attempts" env:"ATTEMPTS"`
+	// Backoff is the type of the backoff, "fixed" or "exponential"
+	Backoff BackoffType `yaml:"backoff" env:"BACKOFF"`
+	// BaseDelay before the first retry
+	BaseDelay time.Duration `yaml:"baseDelay" env:"BASE_DELAY"`
+	// MaxDelay between retries
+	MaxDelay time.Duration `yaml:"maxDelay" env:"MAX_DELAY"`
+	// Jitter is the fraction of the delay, between 0 and 1, by which it is randomized
+	Jitter float64 `yaml:"jitter" env:"JITTER"`
+	// RetryableStatuses are status codes of responses which are retried. Environment variables list them separated
+	// with commas.
+	RetryableStatuses []int `yaml:"retryableStatuses" env:"RETRYABLE_STATUSES"`
+	// AttemptTimeout limits the duration of a single attempt
+	AttemptTimeout time.Duration `yaml:"attemptTimeout" env:"ATTEMPT_TIMEOUT"`
+	// MaxRetryAfter caps the delay requested by Retry-After headers
+	MaxRetryAfter time.Duration `yaml:"maxRetryAfter" env:"MAX_RETRY_AFTER"`
+	// CircuitBreaker configures per-host circuit breakers
+	CircuitBreaker CircuitBreakerSettings `yaml:"circuitBreaker" env:"CIRCUIT_BREAKER_"`
+}
+
+// CircuitBreakerSettings is the declarative counterpart of CircuitBreakerConfig
+type CircuitBreakerSettings struct {
+	Enabled          bool          `yaml:"enabled" env:"ENABLED"`
+	FailureRatio     float64       `yaml:"failureRatio" env:"FAILURE_RATIO"`
+	MinRequests      int           `yaml:"minRequests" env:"MIN_REQUESTS"`
+	Window           time.Duration `yaml:"window" env:"WINDOW"`
+	CoolDown         time.Duration `yaml:"coolDown" env:"COOL_DOWN"`
+	HalfOpenRequests int           `yaml:"halfOpenRequests" env:"HALF_OPEN_REQUESTS"`
+}
+
+// LoadConfigFromYAML parses and validates Config. Unknown fields are rejected. Durations are written like "1.5s".
+func LoadConfigFromYAML(data []byte) (Config, error) {
+	var config Config
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return Config{}, fmt.Errorf("while parsing resilience config: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// LoadConfigFromEnv reads and validates Config from environment variables with given prefix, e.g. with prefix
+// "APP_HTTP_" the number of attempts is read from APP_HTTP_ATTEMPTS and the failure ratio of circuit breakers from
+// APP_HTTP_CIRCUIT_BREAKER_FAILURE_RATIO.
+func LoadConfigFromEnv(prefix string) (Config, error) {
+	var config Config
+	if err := config.OverrideFromEnv(prefix); err != nil {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// OverrideFromEnv sets fields of the config for which environment variables with given prefix are set, e.g. to
+// override values loaded from YAML. Call Validate afterwards.
+func (c *Config) OverrideFromEnv(prefix string) error {
+	return setFromEnv(reflect.ValueOf(c).Elem(), prefix)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setFromEnv(v reflect.Value, prefix string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		name := prefix + field.Tag.Get("env")
+		if field.Type.Kind() == reflect.Struct {
+			if err := setFromEnv(value, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(value, strings.TrimSpace(raw)); err != nil {
+			return fmt.Errorf("invalid value of %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(i))
+	case reflect.Uint:
+		u, err := strconv.ParseUint(raw, 10, 0)
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		if raw != "" {
+			items = strings.Split(raw, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// Validate returns an error listing all invalid fields of the config
+func (c Config) Validate() error {
+	var problems []string
+	check := func(valid bool, format string, args ...interface{}) {
+		if !valid {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Backoff == "" || c.Backoff == FixedBackoff || c.Backoff == ExponentialBackoff,
+		"backoff must be %q or %q, got %q", FixedBackoff, ExponentialBackoff, c.Backoff)
+	check(c.BaseDelay >= 0, "baseDelay must not be negative")
+	check(c.MaxDelay >= 0, "maxDelay must not be negative")
+	check(c.MaxDelay == 0 || c.MaxDelay >= c.BaseDelay, "maxDelay must not be shorter than baseDelay")
+	check(c.Jitter >= 0 && c.Jitter <= 1, "jitter must be between 0 and 1, got %v", c.Jitter)
+	for _, status := range c.RetryableStatuses {
+		check(status >= 100 && status <= 599, "retryableStatuses must be HTTP status codes, got %d", status)
+	}
+	check(c.AttemptTimeout >= 0, "attemptTimeout must not be negative")
+	check(c.MaxRetryAfter >= 0, "maxRetryAfter must not be negative")
+
+	cb := c.CircuitBreaker
+	check(cb.FailureRatio >= 0 && cb.FailureRatio <= 1, "circuitBreaker.failureRatio must be between 0 and 1, got %v",
+		cb.FailureRatio)
+	check(cb.MinRequests >= 0, "circuitBreaker.minRequests must not be negative")
+	check(cb.Window >= 0, "circuitBreaker.window must not be negative")
+	check(cb.CoolDown >= 0, "circuitBreaker.coolDown must not be negative")
+	check(cb.HalfOpenRequests >= 0, "circuitBreaker.halfOpenRequests must not be negative")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid resilience config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// WrapHttpClient validates the config and returns new WrappedHttpClient configured with it. Given retry options, e.g.
+// RetryIf, are applied after the ones derived from the config, but delays are always computed by the configured
+// backoff.
+func (c Config) WrapHttpClient(client HttpClient, opts ...retry.Option) (*WrappedHttpClient, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	var configOpts []retry.Option
+	if c.Attempts > 0 {
+		configOpts = append(configOpts, retry.Attempts(c.Attempts))
+	}
+	wrapped := WrapHttpClient(client, append(configOpts, opts...)...).
+		WithBackoff(Backoff{
+			Type:      c.Backoff,
+			BaseDelay: c.BaseDelay,
+			MaxDelay:  c.MaxDelay,
+			Jitter:    c.Jitter,
+		}).
+		WithAttemptTimeout(c.AttemptTimeout)
+	if len(c.RetryableStatuses) > 0 {
+		wrapped.WithRetryableStatus(RetryOnStatusCodes(c.RetryableStatuses...))
+	}
+	if c.MaxRetryAfter > 0 {
+		wrapped.WithMaxRetryAfter(c.MaxRetryAfter)
+	}
+	if cb := c.CircuitBreaker; cb.Enabled {
+		wrapped.WithCircuitBreaker(CircuitBreakerConfig{
+			FailureRatio:     cb.FailureRatio,
+			MinRequests:      cb.MinRequests,
+			Window:           cb.Window,
+			CoolDown:         cb.CoolDown,
+			HalfOpenRequests: cb.HalfOpenRequests,
+		})
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,186 @@
+package resilient_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFromYAML(t *testing.T) {
+	t.Run("should load config", func(t *testing.T) {
+		// given
+		data := []byte(`
+attempts: 5
+backoff: exponential
+baseDelay: 50ms
+maxDelay: 2s
+jitter: 0.2
+retryableStatuses: [502, 503]
+attemptTimeout: 1.5s
+maxRetryAfter: 10s
+circuitBreaker:
+  enabled: true
+  failureRatio: 0.3
+  minRequests: 20
+  window: 1m
+  coolDown: 15s
+  halfOpenRequests: 2
+`)
+
+		// when
+		config, err := resilient.LoadConfigFromYAML(data)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, resilient.Config{
+			Attempts:          5,
+			Backoff:           resilient.ExponentialBackoff,
+			BaseDelay:         50 * time.Millisecond,
+			MaxDelay:          2 * time.Second,
+			Jitter:            0.2,
+			RetryableStatuses: []int{502, 503},
+			AttemptTimeout:    1500 * time.Millisecond,
+			MaxRetryAfter:     10 * time.Second,
+			CircuitBreaker: resilient.CircuitBreakerSettings{
+				Enabled:          true,
+				FailureRatio:     0.3,
+				MinRequests:      20,
+				Window:           time.Minute,
+				CoolDown:         15 * time.Second,
+				HalfOpenRequests: 2,
+			},
+		}, config)
+	})
+
+	t.Run("should reject unknown fields", func(t *testing.T) {
+		// when
+		_, err := resilient.LoadConfigFromYAML([]byte("attempt: 5"))
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "attempt")
+	})
+
+	t.Run("should report all invalid fields", func(t *testing.T) {
+		// given
+		data := []byte(`
+backoff: linear
+baseDelay: 1s
+maxDelay: 100ms
+jitter: 2
+retryableStatuses: [503, 1000]
+circuitBreaker:
+  failureRatio: 1.5
+`)
+
+		// when
+		_, err := resilient.LoadConfigFromYAML(data)
+
+		// then
+		require.Error(t, err)
+		for _, problem := range []string{
+			`backoff must be "fixed" or "exponential", got "linear"`,
+			"maxDelay must not be shorter than baseDelay",
+			"jitter must be between 0 and 1, got 2",
+			"retryableStatuses must be HTTP status codes, got 1000",
+			"circuitBreaker.failureRatio must be between 0 and 1, got 1.5",
+		} {
+			assert.Contains(t, err.Error(), problem)
+		}
+	})
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Run("should load config", func(t *testing.T) {
+		// given
+		t.Setenv("TEST_HTTP_ATTEMPTS", "4")
+		t.Setenv("TEST_HTTP_BACKOFF", "fixed")
+		t.Setenv("TEST_HTTP_BASE_DELAY", "20ms")
+		t.Setenv("TEST_HTTP_RETRYABLE_STATUSES", "429, 503")
+		t.Setenv("TEST_HTTP_CIRCUIT_BREAKER_ENABLED", "true")
+		t.Setenv("TEST_HTTP_CIRCUIT_BREAKER_COOL_DOWN", "5s")
+
+		// when
+		config, err := resilient.LoadConfigFromEnv("TEST_HTTP_")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, resilient.Config{
+			Attempts:          4,
+			Backoff:           resilient.FixedBackoff,
+			BaseDelay:         20 * time.Millisecond,
+			RetryableStatuses: []int{429, 503},
+			CircuitBreaker: resilient.CircuitBreakerSettings{
+				Enabled:  true,
+				CoolDown: 5 * time.Second,
+			},
+		}, config)
+	})
+
+	t.Run("should override config loaded from YAML", func(t *testing.T) {
+		// given
+		config, err := resilient.LoadConfigFromYAML([]byte("attempts: 5\nbaseDelay: 1s"))
+		require.NoError(t, err)
+		t.Setenv("TEST_HTTP_ATTEMPTS", "2")
+
+		// when
+		err = config.OverrideFromEnv("TEST_HTTP_")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, uint(2), config.Attempts)
+		assert.Equal(t, time.Second, config.BaseDelay)
+	})
+
+	t.Run("should return error with the name of invalid variable", func(t *testing.T) {
+		// given
+		t.Setenv("TEST_HTTP_MAX_DELAY", "soon")
+
+		// when
+		_, err := resilient.LoadConfigFromEnv("TEST_HTTP_")
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "TEST_HTTP_MAX_DELAY")
+	})
+}
+
+func TestConfigWrapHttpClient(t *testing.T) {
+	t.Run("should build client which retries according to config", func(t *testing.T) {
+		// given
+		config := resilient.Config{
+			Attempts:          3,
+			Backoff:           resilient.FixedBackoff,
+			BaseDelay:         20 * time.Millisecond,
+			RetryableStatuses: []int{http.StatusBadGateway},
+		}
+		mock := &statusHttpClient{statuses: []int{http.StatusBadGateway, 0, http.StatusOK}}
+
+		// when
+		wrapped, err := config.WrapHttpClient(mock)
+		require.NoError(t, err)
+		start := time.Now()
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	})
+
+	t.Run("should return error for invalid config", func(t *testing.T) {
+		// given
+		config := resilient.Config{Jitter: -1}
+
+		// when
+		_, err := config.WrapHttpClient(&mockHttpClient{})
+
+		// then
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,105 @@
+package resilient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithAttemptTimeout limits the duration of a single attempt, including reading the response body. It is independent
+// of the deadline of the request context, which limits all attempts together.
+func (c *WrappedHttpClient) WithAttemptTimeout(timeout time.Duration) *WrappedHttpClient {
+	c.attemptTimeout = timeout
+	return c
+}
+
+// doWithTimeout calls underlying HttpClient with the attempt timeout applied
+func (c *WrappedHttpClient) doWithTimeout(req *http.Request) (*http.Response, error) {
+	if c.attemptTimeout <= 0 {
+		return c.underlying.Do(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.attemptTimeout)
+	resp, err := c.underlying.Do(req.WithContext(ctx))
+	if err != nil || resp == nil || resp.Body == nil {
+		cancel()
+		return resp, err
+	}
+	// the response body is read after the attempt returns, so the context is cancelled when it gets closed
+	resp.Body = onClose(resp.Body, cancel)
+	return resp, nil
+}
+
+// onClose wraps the body, so that given function is called once the body is closed
+func onClose(body io.ReadCloser, fn func()) io.ReadCloser {
+	return &closeHook{ReadCloser: body, fn: fn}
+}
+
+type closeHook struct {
+	io.ReadCloser
+	fn   func()
+	once sync.Once
+}
+
+func (b *closeHook) Close() error {
+	defer b.once.Do(b.fn)
+	return b.ReadCloser.Close()
+}
+
+// waitUntil sleeps until given time or until the context is done, in which case the context error is returned
+func waitUntil(ctx context.Context, t time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	delay := time.Until(t)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// untilDone returns the result of fn, or the context error as soon as the context is done, so that the caller doesn't
+// wait for an attempt which doesn't look at the context. A result which arrives after the context is done is passed to
+// discard, if it is not nil.
+func untilDone[T any](ctx context.Context, fn func() (T, error), discard func(T)) (T, error) {
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	results := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		results <- result{value: value, err: err}
+	}()
+	select {
+	case r := <-results:
+		return r.value, r.err
+	case <-ctx.Done():
+		select {
+		case r := <-results:
+			return r.value, r.err
+		default:
+		}
+		if discard != nil {
+			go func() {
+				discard((<-results).value)
+			}()
+		}
+		var zero T
+		return zero, ctx.Err()
+	}
+}
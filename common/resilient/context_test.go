@@ -0,0 +1,107 @@
+package resilient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowHttpClient blocks until the request context is done for the first blockingCalls calls
+type slowHttpClient struct {
+	calls         int32
+	blockingCalls int32
+}
+
+func (c *slowHttpClient) Do(req *http.Request) (*http.Response, error) {
+	if atomic.AddInt32(&c.calls, 1) <= c.blockingCalls {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return &http.Response{StatusCode: http.StatusTeapot}, nil
+}
+
+func TestHttpClientContext(t *testing.T) {
+	t.Run("should stop retrying when context is done", func(t *testing.T) {
+		// given
+		mock := &slowHttpClient{blockingCalls: 100}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(100 * time.Millisecond))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		start := time.Now()
+		_, err = wrapped.Do(req)
+
+		// then
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+		time.Sleep(200 * time.Millisecond)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&mock.calls))
+	})
+
+	t.Run("should return context error while waiting between attempts", func(t *testing.T) {
+		// given
+		mock := &mockHttpClient{successAfter: 10}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Second))
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		time.AfterFunc(20*time.Millisecond, cancel)
+		start := time.Now()
+		_, err = wrapped.Do(req)
+
+		// then
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("should not call underlying client with cancelled context", func(t *testing.T) {
+		// given
+		mock := &slowHttpClient{}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		assert.True(t, errors.Is(err, context.Canceled))
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&mock.calls))
+	})
+
+	t.Run("should retry attempts which exceeded attempt timeout", func(t *testing.T) {
+		// given
+		mock := &slowHttpClient{blockingCalls: 2}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithAttemptTimeout(20 * time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		resp, err := wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&mock.calls))
+	})
+}
@@ -0,0 +1,124 @@
+package resilient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// permanentError marks an error of an attempt after which no more attempts are made
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the error, so that the operation or request which returned it is not retried, regardless of the
+// retryable error classifier. The error is returned to the caller without the mark. Permanent returns nil for nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// unmarkPermanent removes the mark added by Permanent, so that the error is returned as it was marked
+func unmarkPermanent(err error) error {
+	if permanentErr, ok := err.(*permanentError); ok {
+		return permanentErr.err
+	}
+	return err
+}
+
+// IsPermanentError returns true for errors which will occur again on retry: errors marked with Permanent, DNS lookups
+// of hosts which don't exist, TLS certificate failures, TLS handshakes with non-TLS servers and malformed URLs. Other
+// errors, e.g. timeouts, refused or reset connections and temporary DNS failures, are transient.
+func IsPermanentError(err error) bool {
+	var permanentErr *permanentError
+	if errors.As(err, &permanentErr) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsNotFound && !dnsErr.IsTemporary && !dnsErr.IsTimeout
+	}
+
+	var (
+		unknownAuthorityErr   x509.UnknownAuthorityError
+		certificateInvalidErr x509.CertificateInvalidError
+		hostnameErr           x509.HostnameError
+		recordHeaderErr       tls.RecordHeaderError
+		escapeErr             url.EscapeError
+		invalidHostErr        url.InvalidHostError
+	)
+	if errors.As(err, &unknownAuthorityErr) || errors.As(err, &certificateInvalidErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &recordHeaderErr) || errors.As(err, &escapeErr) || errors.As(err, &invalidHostErr) {
+		return true
+	}
+	return isMalformedRequestError(err)
+}
+
+// isMalformedRequestError recognizes errors of http.Client for URLs it can't send requests to. They are created with
+// errors.New, so only their messages can be checked.
+func isMalformedRequestError(err error) bool {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+	message := urlErr.Err.Error()
+	return strings.HasPrefix(message, "unsupported protocol scheme") || message == "http: no Host in request URL"
+}
+
+// causeError is a sentinel error with the error which caused it. errors.Is matches both, while errors.As and Unwrap
+// reach the cause.
+type causeError struct {
+	sentinel error
+	cause    error
+}
+
+func (e *causeError) Error() string {
+	return e.sentinel.Error() + ": " + e.cause.Error()
+}
+
+func (e *causeError) Unwrap() error {
+	return e.cause
+}
+
+func (e *causeError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+// withCause returns the sentinel error with given cause. It returns the sentinel if the cause is nil.
+func withCause(sentinel, cause error) error {
+	if cause == nil {
+		return sentinel
+	}
+	return &causeError{sentinel: sentinel, cause: cause}
+}
+
+// RetryableErrorFunc decides whether an operation or request which failed with given error should be retried
+type RetryableErrorFunc func(err error) bool
+
+// DefaultRetryableError treats all errors as retryable except permanent ones, see IsPermanentError. Errors of a done
+// context are retryable, because they may come from a timeout of a single attempt, while retries stop anyway once the
+// context of the whole operation is done.
+func DefaultRetryableError(err error) bool {
+	return !IsPermanentError(err)
+}
+
+// WithRetryableError sets the function which decides whether a request which failed with given error should be
+// retried. Responses with retryable status codes are not passed to it. DefaultRetryableError is used by default.
+func (c *WrappedHttpClient) WithRetryableError(retryable RetryableErrorFunc) *WrappedHttpClient {
+	c.policy.WithRetryableError(retryable)
+	return c
+}
@@ -0,0 +1,171 @@
+package resilient_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPermanentError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err       error
+		permanent bool
+	}{
+		"marked error":          {err: fmt.Errorf("while calling: %w", resilient.Permanent(errSome)), permanent: true},
+		"host not found":        {err: urlError(&net.DNSError{Err: "no such host", Name: "nope.example.com", IsNotFound: true}), permanent: true},
+		"unknown authority":     {err: urlError(x509.UnknownAuthorityError{}), permanent: true},
+		"invalid certificate":   {err: urlError(x509.CertificateInvalidError{Reason: x509.Expired}), permanent: true},
+		"wrong hostname":        {err: urlError(x509.HostnameError{Host: "example.com", Certificate: &x509.Certificate{}}), permanent: true},
+		"invalid URL escape":    {err: url.EscapeError("%zz"), permanent: true},
+		"unsupported scheme":    {err: urlError(errors.New(`unsupported protocol scheme "ftp"`)), permanent: true},
+		"temporary DNS failure": {err: urlError(&net.DNSError{Err: "server misbehaving", IsTemporary: true})},
+		"refused connection":    {err: urlError(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})},
+		"timeout":               {err: urlError(context.DeadlineExceeded)},
+		"other error":           {err: errSome},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.permanent, resilient.IsPermanentError(tc.err))
+			assert.Equal(t, !tc.permanent, resilient.DefaultRetryableError(tc.err))
+		})
+	}
+
+	t.Run("should not mark nil", func(t *testing.T) {
+		assert.Nil(t, resilient.Permanent(nil))
+	})
+}
+
+func urlError(err error) error {
+	return &url.Error{Op: "Get", URL: "https://example.com/", Err: err}
+}
+
+// errorHttpClient fails every call with given error
+type errorHttpClient struct {
+	calls int
+	err   error
+}
+
+func (c *errorHttpClient) Do(*http.Request) (*http.Response, error) {
+	c.calls++
+	return nil, c.err
+}
+
+func TestHttpClientErrorClassification(t *testing.T) {
+	t.Run("should not retry host which doesn't exist", func(t *testing.T) {
+		// given
+		mock := &errorHttpClient{err: urlError(&net.DNSError{Err: "no such host", Name: "nope.example.com", IsNotFound: true})}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+
+		// when
+		_, err := wrapped.Get("http://nope.example.com/")
+
+		// then
+		var dnsErr *net.DNSError
+		assert.True(t, errors.As(err, &dnsErr))
+		assert.Equal(t, 1, mock.calls)
+	})
+
+	t.Run("should not retry TLS certificate failures", func(t *testing.T) {
+		// given
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		observer := &recordingObserver{}
+		wrapped := resilient.NewHttpClient(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithObserver(observer)
+
+		// when
+		_, err := wrapped.Get(server.URL)
+
+		// then
+		require.Error(t, err)
+		assert.Len(t, observer.attempts, 1)
+	})
+
+	t.Run("should not retry malformed URLs", func(t *testing.T) {
+		// given
+		observer := &recordingObserver{}
+		wrapped := resilient.NewHttpClient(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithObserver(observer)
+
+		// when
+		_, err := wrapped.Get("ftp://example.com/")
+
+		// then
+		require.Error(t, err)
+		assert.Len(t, observer.attempts, 1)
+	})
+
+	t.Run("should retry transient errors", func(t *testing.T) {
+		// given
+		mock := &errorHttpClient{err: urlError(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		assert.Contains(t, err.Error(), syscall.ECONNREFUSED.Error())
+		assert.Equal(t, 3, mock.calls)
+	})
+
+	t.Run("should not retry errors marked as permanent", func(t *testing.T) {
+		// given
+		mock := &errorHttpClient{err: resilient.Permanent(errSome)}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		assert.Equal(t, errSome, err)
+		assert.Equal(t, 1, mock.calls)
+	})
+
+	t.Run("should use configured classifier", func(t *testing.T) {
+		// given
+		mock := &errorHttpClient{err: errSome}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableError(func(err error) bool {
+				return !errors.Is(err, errSome)
+			})
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		assert.Equal(t, errSome, err)
+		assert.Equal(t, 1, mock.calls)
+	})
+}
+
+func TestExecutePermanentError(t *testing.T) {
+	// given
+	calls := 0
+	policy := resilient.NewPolicy(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+		WithRetryableError(func(error) bool {
+			return true
+		})
+
+	// when
+	_, err := resilient.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		calls++
+		return "", fmt.Errorf("while publishing: %w", resilient.Permanent(errSome))
+	}, policy)
+
+	// then
+	assert.True(t, errors.Is(err, errSome))
+	assert.Equal(t, 1, calls)
+}
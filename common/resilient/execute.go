@@ -0,0 +1,250 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	retry "github.com/avast/retry-go"
+)
+
+// Policy declares how operations are retried. It is the retry loop used by Execute, WrappedHttpClient and GRPCRetry,
+// so that retries of HTTP requests, gRPC calls and other operations, e.g. Kubernetes API calls or message publishes,
+// behave the same. The same policy can be shared by all of them. A policy can be used by multiple goroutines once
+// configured.
+type Policy struct {
+	opts            []retry.Option
+	attempts        uint
+	backoff         Backoff
+	retryableError  RetryableErrorFunc
+	circuitBreaker  *CircuitBreaker
+	circuitBreakers *circuitBreakers
+	retryBudgets    *RetryBudgets
+	budgetKey       string
+}
+
+// DefaultAttempts is the maximal number of attempts unless it is set with retry.Attempts or WithAttempts. It is the
+// same as the default of retry-go.
+const DefaultAttempts = 10
+
+// NewPolicy returns new Policy which retries operations according to given options. Errors of operations are returned
+// as they are, so that they can be inspected with errors.Is and errors.As. Delays between retries are computed by the
+// backoff, see WithBackoff, as retry-go sleeps without looking at the context, so Delay and DelayType options have no
+// effect.
+func NewPolicy(opts ...retry.Option) *Policy {
+	return newPolicy(append([]retry.Option{retry.LastErrorOnly(true)}, opts...))
+}
+
+func newPolicy(opts []retry.Option) *Policy {
+	return &Policy{
+		opts:           opts,
+		attempts:       countAttempts(opts),
+		retryableError: DefaultRetryableError,
+	}
+}
+
+// clone returns a copy of the policy, which can be configured without affecting the policy. Circuit breakers and retry
+// budgets are shared with the policy.
+func (p *Policy) clone() *Policy {
+	cloned := *p
+	return &cloned
+}
+
+// WithRetryableError sets the function which decides whether an operation should be retried. DefaultRetryableError is
+// used by default.
+func (p *Policy) WithRetryableError(retryable RetryableErrorFunc) *Policy {
+	p.retryableError = retryable
+	return p
+}
+
+// WithAttempts sets the maximal number of attempts of an operation, including the first one
+func (p *Policy) WithAttempts(attempts uint) *Policy {
+	p.attempts = attempts
+	return p
+}
+
+// WithBackoff makes the policy wait between retries according to given backoff. By default it is the exponential
+// backoff with DefaultBaseDelay.
+func (p *Policy) WithBackoff(backoff Backoff) *Policy {
+	p.backoff = backoff
+	return p
+}
+
+// WithCircuitBreaker makes the policy run every attempt through given circuit breaker. Failed attempts count as
+// failures, except errors marked with Permanent. Operations rejected by an open circuit are not retried and return an
+// error wrapping ErrCircuitOpen.
+func (p *Policy) WithCircuitBreaker(cb *CircuitBreaker) *Policy {
+	p.circuitBreaker = cb
+	return p
+}
+
+// WithRetryBudget makes the policy count operations and retries in the budget of given key, e.g. the name of the
+// dependency, and stop retrying once it is exhausted. In that case ErrRetryBudgetExhausted wrapping the last error is
+// returned. If the key is empty, WrappedHttpClient uses the host of the request and GRPCRetry the target of the
+// connection as the key.
+func (p *Policy) WithRetryBudget(budgets *RetryBudgets, key string) *Policy {
+	p.retryBudgets = budgets
+	p.budgetKey = key
+	return p
+}
+
+// Execute runs the operation and retries it according to the policy. Every attempt gets the given context. Retries
+// stop as soon as the context is done and its error is returned. A nil policy means NewPolicy without options.
+func Execute[T any](ctx context.Context, operation func(ctx context.Context) (T, error), policy *Policy) (T, error) {
+	if policy == nil {
+		policy = NewPolicy()
+	}
+	return untilDone(ctx, func() (T, error) {
+		var value T
+		result := policy.run(ctx, runOptions{
+			attempt: func(int) attemptResult {
+				var err error
+				value, err = operation(ctx)
+				return attemptResult{err: err}
+			},
+		})
+		if result.err != nil {
+			var zero T
+			return zero, result.err
+		}
+		return value, nil
+	}, nil)
+}
+
+// retryDecision overrides the retryable error classifier of the policy for the error of an attempt
+type retryDecision int
+
+const (
+	// classifyError leaves the decision to the retryable error classifier of the policy
+	classifyError retryDecision = iota
+	retryError
+	stopRetrying
+)
+
+// attemptResult is the outcome of a single attempt run by Policy.run
+type attemptResult struct {
+	err error
+	// failed marks an attempt without error which still counts as a failure for the circuit breaker, e.g. a response
+	// with a failure status code
+	failed bool
+	// retryAfter is the delay before the next attempt requested by the server, e.g. with Retry-After. A longer backoff
+	// delay takes precedence.
+	retryAfter time.Duration
+	decision   retryDecision
+}
+
+// runOptions describe attempts run by Policy.run
+type runOptions struct {
+	// key selects the circuit breaker and the retry budget, e.g. the host of a request
+	key string
+	// once disables retries, e.g. for requests which are not idempotent
+	once bool
+	// attempt is called with the number of the attempt, starting from 1
+	attempt func(number int) attemptResult
+	// retrying is called before waiting for the next attempt. It is optional.
+	retrying func()
+}
+
+// runResult is the outcome of all attempts run by Policy.run
+type runResult struct {
+	attempts int
+	// err is nil if the last attempt succeeded. Otherwise it is the reason why retrying stopped, ErrRetryBudgetExhausted
+	// wrapping the last error if a retry was refused, or the error returned by retry-go when attempts ran out.
+	err error
+	// stopped is true if retrying stopped because the context was done or the error can't be retried
+	stopped bool
+	// retryRefused is true if a retry was refused by the retry budget
+	retryRefused bool
+}
+
+// run runs attempts according to the policy until one succeeds. An attempt which has to stop retrying records the
+// reason in stopErr and reports success, so that retry.Do returns immediately. The number of attempts is passed to
+// retry-go, while its delays are disabled and the backoff is waited for in waitUntil instead, so that retrying stops
+// as soon as the context is done.
+func (p *Policy) run(ctx context.Context, options runOptions) runResult {
+	attempts := p.attempts
+	if options.once || attempts < 1 {
+		attempts = 1
+	}
+	opts := append(p.opts[:len(p.opts):len(p.opts)],
+		retry.Attempts(attempts), retry.Delay(0), retry.DelayType(retry.FixedDelay))
+	budget := p.retryBudget(options.key)
+	cb := p.circuitBreakerFor(options.key)
+
+	var result runResult
+	var lastErr, stopErr error
+	var notBefore time.Time
+	err := retry.Do(func() error {
+		if result.attempts > 0 {
+			if budget != nil && !budget.retry() {
+				result.retryRefused = true
+				return nil
+			}
+			if options.retrying != nil {
+				options.retrying()
+			}
+		}
+		if stopErr = waitUntil(ctx, notBefore); stopErr != nil {
+			return nil
+		}
+
+		result.attempts++
+		attempt := guard(ctx, cb, func() attemptResult {
+			return options.attempt(result.attempts)
+		})
+		lastErr = attempt.err
+		if lastErr == nil {
+			return nil
+		}
+		var permanentErr *permanentError
+		if errors.As(lastErr, &permanentErr) || attempt.decision == stopRetrying ||
+			(attempt.decision == classifyError && !p.retryableError(lastErr)) {
+			stopErr = unmarkPermanent(lastErr)
+			return nil
+		}
+		delay := p.backoff.Delay(result.attempts)
+		if attempt.retryAfter > delay {
+			delay = attempt.retryAfter
+		}
+		notBefore = time.Now().Add(delay)
+		return lastErr
+	}, opts...)
+
+	switch {
+	case stopErr != nil:
+		result.err, result.stopped = stopErr, true
+	case result.retryRefused:
+		result.err = withCause(ErrRetryBudgetExhausted, lastErr)
+	default:
+		result.err = err
+	}
+	return result
+}
+
+// retries returns true if the policy allows retries at all
+func (p *Policy) retries() bool {
+	return p.attempts > 1
+}
+
+// guard runs the attempt once it is allowed by the circuit breaker, if there is one
+func guard(ctx context.Context, cb *CircuitBreaker, attempt func() attemptResult) attemptResult {
+	if cb == nil {
+		return attempt()
+	}
+
+	generation, err := cb.allow()
+	if err != nil {
+		return attemptResult{err: Permanent(fmt.Errorf("%w: %s", err, cb.name))}
+	}
+	result := attempt()
+	var permanentErr *permanentError
+	if ctx.Err() != nil || errors.As(result.err, &permanentErr) {
+		// the attempt was cancelled by the caller or rejected before reaching the dependency, so it says nothing
+		// about the dependency
+		cb.release(generation)
+	} else {
+		cb.record(generation, result.err == nil && !result.failed)
+	}
+	return result
+}
@@ -0,0 +1,225 @@
+package resilient_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errSome = errors.New("some error")
+
+// failingOperation fails until given call. Calls are counted atomically, as the operation may still run after Execute
+// returned because the context is done.
+func failingOperation(calls *int32, successAfter int32) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if atomic.AddInt32(calls, 1) < successAfter {
+			return "", errSome
+		}
+		return "ok", nil
+	}
+}
+
+func TestExecute(t *testing.T) {
+	t.Run("should retry until success", func(t *testing.T) {
+		// given
+		var calls int32
+		policy := resilient.NewPolicy(retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+
+		// when
+		value, err := resilient.Execute(context.Background(), failingOperation(&calls, 3), policy)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "ok", value)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should return last error when attempts run out", func(t *testing.T) {
+		// given
+		var calls int32
+		policy := resilient.NewPolicy(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+
+		// when
+		value, err := resilient.Execute(context.Background(), failingOperation(&calls, 100), policy)
+
+		// then
+		assert.Equal(t, errSome, err)
+		assert.Empty(t, value)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should not retry non-retryable errors", func(t *testing.T) {
+		// given
+		var calls int32
+		policy := resilient.NewPolicy(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableError(func(err error) bool {
+				return !errors.Is(err, errSome)
+			})
+
+		// when
+		_, err := resilient.Execute(context.Background(), failingOperation(&calls, 100), policy)
+
+		// then
+		assert.Equal(t, errSome, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should wait according to backoff", func(t *testing.T) {
+		// given
+		var calls int32
+		policy := resilient.NewPolicy(retry.Attempts(3)).
+			WithBackoff(resilient.Backoff{Type: resilient.FixedBackoff, BaseDelay: 20 * time.Millisecond})
+
+		// when
+		start := time.Now()
+		_, err := resilient.Execute(context.Background(), failingOperation(&calls, 3), policy)
+
+		// then
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	})
+
+	t.Run("should stop waiting when context is done", func(t *testing.T) {
+		// given
+		var calls int32
+		policy := resilient.NewPolicy(retry.Attempts(3)).
+			WithBackoff(resilient.Backoff{Type: resilient.FixedBackoff, BaseDelay: time.Second})
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		// when
+		start := time.Now()
+		_, err := resilient.Execute(ctx, failingOperation(&calls, 3), policy)
+
+		// then
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should make attempts set with WithAttempts", func(t *testing.T) {
+		// given
+		var calls int32
+		policy := resilient.NewPolicy(retry.Attempts(2)).WithBackoff(fixedBackoff(time.Millisecond)).WithAttempts(4)
+
+		// when
+		_, err := resilient.Execute(context.Background(), failingOperation(&calls, 100), policy)
+
+		// then
+		assert.Equal(t, errSome, err)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should make default number of attempts", func(t *testing.T) {
+		// given
+		var calls int32
+		policy := resilient.NewPolicy().WithBackoff(fixedBackoff(time.Millisecond))
+
+		// when
+		_, err := resilient.Execute(context.Background(), failingOperation(&calls, 100), policy)
+
+		// then
+		assert.Equal(t, errSome, err)
+		assert.Equal(t, int32(resilient.DefaultAttempts), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should stop retrying when circuit opens", func(t *testing.T) {
+		// given
+		var calls int32
+		cb := resilient.NewCircuitBreaker("database", resilient.CircuitBreakerConfig{MinRequests: 2, CoolDown: time.Minute})
+		policy := resilient.NewPolicy(retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).WithCircuitBreaker(cb)
+
+		// when
+		_, err := resilient.Execute(context.Background(), failingOperation(&calls, 100), policy)
+
+		// then
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, resilient.ErrCircuitOpen))
+		assert.Contains(t, err.Error(), "database")
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		assert.Equal(t, resilient.CircuitOpen, cb.State())
+	})
+
+	t.Run("should refuse retries once budget is exhausted", func(t *testing.T) {
+		// given
+		var calls int32
+		budgets := resilient.NewRetryBudgets(resilient.RetryBudgetConfig{
+			Ratio:               0.5,
+			MinRetriesPerSecond: 0.1,
+			Window:              10 * time.Second,
+		})
+		policy := resilient.NewPolicy(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryBudget(budgets, "queue")
+
+		// when
+		_, err := resilient.Execute(context.Background(), failingOperation(&calls, 100), policy)
+
+		// then
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, resilient.ErrRetryBudgetExhausted))
+		assert.True(t, errors.Is(err, errSome))
+		assert.Equal(t, errSome, errors.Unwrap(err))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should share circuit breaker with http client", func(t *testing.T) {
+		// given
+		var calls int32
+		cb := resilient.NewCircuitBreaker("backend", resilient.CircuitBreakerConfig{MinRequests: 2, CoolDown: time.Minute})
+		policy := resilient.NewPolicy(retry.Attempts(2)).WithBackoff(fixedBackoff(time.Millisecond)).WithCircuitBreaker(cb)
+		mock := &mockHttpClient{successAfter: 100}
+		wrapped := resilient.WrapHttpClient(mock).WithPolicy(policy)
+
+		// when
+		_, executeErr := resilient.Execute(context.Background(), failingOperation(&calls, 100), policy)
+		_, httpErr := wrapped.Get("http://example.com/")
+
+		// then
+		assert.Equal(t, errSome, executeErr)
+		require.Error(t, httpErr)
+		assert.True(t, errors.Is(httpErr, resilient.ErrCircuitOpen))
+		assert.Equal(t, 0, mock.calls)
+		assert.Equal(t, resilient.CircuitOpen, wrapped.CircuitState("example.com"))
+	})
+
+	t.Run("should not be changed by clients using it", func(t *testing.T) {
+		// given
+		var calls int32
+		policy := resilient.NewPolicy(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+		resilient.WrapHttpClient(&mockHttpClient{}).
+			WithPolicy(policy).
+			WithRetryableError(func(error) bool { return false }).
+			WithBackoff(resilient.Backoff{BaseDelay: time.Second})
+		resilient.NewGRPCRetry().
+			WithPolicy(policy).
+			WithBackoff(resilient.Backoff{BaseDelay: time.Second})
+
+		// when
+		start := time.Now()
+		_, err := resilient.Execute(context.Background(), failingOperation(&calls, 100), policy)
+
+		// then
+		assert.Equal(t, errSome, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("should run without policy", func(t *testing.T) {
+		// when
+		value, err := resilient.Execute(context.Background(), func(ctx context.Context) (int, error) {
+			return 42, nil
+		}, nil)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 42, value)
+	})
+}
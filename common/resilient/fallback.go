@@ -0,0 +1,268 @@
+package resilient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaleResponseHeader marks responses served from the stale cache, see WithStaleCache
+const StaleResponseHeader = "X-Resilient-Stale"
+
+// Default values used for zero fields of StaleCacheConfig
+const (
+	DefaultStaleMaxAge     = 5 * time.Minute
+	DefaultStaleMaxEntries = 100
+	DefaultStaleMaxBody    = 1 << 20
+)
+
+// FallbackFunc returns the response used when the request failed with given error or a failure status code, in which
+// case the error is nil. The returned error is passed to the caller.
+type FallbackFunc func(req *http.Request, err error) (*http.Response, error)
+
+// WithFallback makes the client call given function when the request fails after all attempts, either with an error or
+// a status code which is a failure according to WithRetryableStatus or DefaultRetryableStatus. It is called after the
+// stale cache had nothing to serve.
+func (c *WrappedHttpClient) WithFallback(fallback FallbackFunc) *WrappedHttpClient {
+	c.fallback = fallback
+	return c
+}
+
+// StaleCacheConfig configures the stale cache. Zero fields are set to default values.
+type StaleCacheConfig struct {
+	// MaxAge of a cached response which can still be served
+	MaxAge time.Duration
+	// MaxEntries is the number of cached URLs. The oldest entry is evicted when the cache is full.
+	MaxEntries int
+	// MaxBodySize of a cached response. Bigger responses are not cached.
+	MaxBodySize int64
+}
+
+func (c StaleCacheConfig) withDefaults() StaleCacheConfig {
+	if c.MaxAge <= 0 {
+		c.MaxAge = DefaultStaleMaxAge
+	}
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = DefaultStaleMaxEntries
+	}
+	if c.MaxBodySize <= 0 {
+		c.MaxBodySize = DefaultStaleMaxBody
+	}
+	return c
+}
+
+// WithStaleCache makes the client keep the last successful response of GET requests per URL in memory and serve it,
+// marked with StaleResponseHeader, when the request fails after all attempts. The cache is shared by all callers of
+// the client, so requests with credentials, i.e. Authorization or Cookie headers or user info in the URL, and requests
+// with Cache-Control no-store are never cached. Neither are responses with Cache-Control no-store or private, responses
+// setting cookies and responses with Vary: *. Stale responses are served only to requests with the same values of
+// headers listed in Vary.
+func (c *WrappedHttpClient) WithStaleCache(config StaleCacheConfig) *WrappedHttpClient {
+	c.staleCache = &staleCache{
+		config:  config.withDefaults(),
+		entries: map[string]*staleEntry{},
+	}
+	return c
+}
+
+// withFallback runs the request and replaces its failure with a stale response or the result of the fallback function
+func (c *WrappedHttpClient) withFallback(req *http.Request, rewinder *bodyRewinder) (*http.Response, error) {
+	resp, err := c.isolate(req, rewinder)
+	if c.staleCache == nil && c.fallback == nil {
+		return resp, err
+	}
+	if err == nil && resp != nil && !c.isFailureStatus(resp.StatusCode) {
+		return c.staleCache.store(req, resp)
+	}
+
+	if stale := c.staleCache.load(req); stale != nil {
+		discardResponse(resp)
+		return stale, nil
+	}
+	if c.fallback == nil {
+		return resp, err
+	}
+	discardResponse(resp)
+	return c.fallback(req, err)
+}
+
+type staleEntry struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+	storedAt   time.Time
+	// vary holds values of request headers listed in the Vary header of the response
+	vary map[string][]string
+}
+
+// staleCache holds the last successful responses of GET requests
+type staleCache struct {
+	config StaleCacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*staleEntry
+}
+
+// cacheableRequest returns false for requests whose responses can't be shared with other callers
+func cacheableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != "" {
+		return false
+	}
+	if req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != "" || req.URL.User != nil {
+		return false
+	}
+	return !hasCacheDirective(req.Header, "no-store")
+}
+
+func cacheableResponse(resp *http.Response) bool {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || resp.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	if hasCacheDirective(resp.Header, "no-store") || hasCacheDirective(resp.Header, "private") {
+		return false
+	}
+	for _, name := range varyHeaders(resp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasCacheDirective returns true if the Cache-Control header contains given directive, with or without arguments
+func hasCacheDirective(header http.Header, directive string) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name := strings.TrimSpace(strings.SplitN(part, "=", 2)[0])
+			if strings.EqualFold(name, directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// varyHeaders returns canonical names of headers listed in the Vary header
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// matchesVary returns true if the request has the same values of headers listed in Vary as the one the entry was
+// stored for
+func (e *staleEntry) matchesVary(req *http.Request) bool {
+	for name, values := range e.vary {
+		if strings.Join(req.Header.Values(name), ",") != strings.Join(values, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// store caches the response if it is successful and small enough. The body is read, so a response with a body which
+// replays it is returned.
+func (s *staleCache) store(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if s == nil || !cacheableRequest(req) || !cacheableResponse(resp) || resp.Body == nil {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, s.config.MaxBodySize+1))
+	if err != nil {
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), errReader{err}), Closer: resp.Body}
+		return resp, nil
+	}
+	if int64(len(body)) > s.config.MaxBodySize {
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+	if err := resp.Body.Close(); err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := req.URL.String()
+	if _, ok := s.entries[key]; !ok && len(s.entries) >= s.config.MaxEntries {
+		s.evictOldest()
+	}
+	vary := map[string][]string{}
+	for _, name := range varyHeaders(resp.Header) {
+		vary[name] = req.Header.Values(name)
+	}
+	s.entries[key] = &staleEntry{
+		statusCode: resp.StatusCode,
+		header:     resp.Header.Clone(),
+		body:       body,
+		storedAt:   time.Now(),
+		vary:       vary,
+	}
+	return resp, nil
+}
+
+// load returns the cached response of the request URL or nil if there is none within the staleness limit
+func (s *staleCache) load(req *http.Request) *http.Response {
+	if s == nil || !cacheableRequest(req) {
+		return nil
+	}
+
+	s.mu.Lock()
+	entry, ok := s.entries[req.URL.String()]
+	s.mu.Unlock()
+	if !ok || !entry.matchesVary(req) {
+		return nil
+	}
+	age := time.Since(entry.storedAt)
+	if age > s.config.MaxAge {
+		return nil
+	}
+
+	header := entry.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(StaleResponseHeader, "true")
+	header.Set("Age", strconv.Itoa(int(age.Seconds())))
+	return &http.Response{
+		Status:        strconv.Itoa(entry.statusCode) + " " + http.StatusText(entry.statusCode),
+		StatusCode:    entry.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+		Request:       req,
+	}
+}
+
+func (s *staleCache) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range s.entries {
+		if oldestKey == "" || entry.storedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.storedAt
+		}
+	}
+	delete(s.entries, oldestKey)
+}
+
+// errReader returns the error which interrupted reading of a body
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,294 @@
+package resilient
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	retry "github.com/avast/retry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PushbackMetadataKey is the trailer key in which a server tells after how many milliseconds a call may be retried. A
+// negative or malformed value means the call must not be retried.
+const PushbackMetadataKey = "grpc-retry-pushback-ms"
+
+// RetryableCodeFunc decides whether a call which failed with given status code should be retried
+type RetryableCodeFunc func(code codes.Code) bool
+
+// DefaultRetryableCode treats UNAVAILABLE, DEADLINE_EXCEEDED and RESOURCE_EXHAUSTED as retryable
+func DefaultRetryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// RetryOnCodes returns RetryableCodeFunc which treats only given codes as retryable
+func RetryOnCodes(retryableCodes ...codes.Code) RetryableCodeFunc {
+	retryable := make(map[codes.Code]bool, len(retryableCodes))
+	for _, code := range retryableCodes {
+		retryable[code] = true
+	}
+	return func(code codes.Code) bool {
+		return retryable[code]
+	}
+}
+
+// GRPCRetry provides gRPC client interceptors which retry calls failed with retryable status codes, with the same
+// retry Policy and Retry-After semantics as WrappedHttpClient
+type GRPCRetry struct {
+	policy        *Policy
+	retryableCode RetryableCodeFunc
+	maxPushback   time.Duration
+}
+
+// NewGRPCRetry returns new GRPCRetry which retries calls according to given options. Errors of calls are returned as
+// they are, so that their status can be read. Delays between retries are computed by the backoff, see WithBackoff, so
+// Delay and DelayType options have no effect.
+func NewGRPCRetry(opts ...retry.Option) *GRPCRetry {
+	return &GRPCRetry{
+		policy:        NewPolicy(opts...),
+		retryableCode: DefaultRetryableCode,
+		maxPushback:   DefaultMaxRetryAfter,
+	}
+}
+
+// WithRetryableCodes sets the function which decides whether a call should be retried. DefaultRetryableCode is used
+// by default.
+func (r *GRPCRetry) WithRetryableCodes(retryable RetryableCodeFunc) *GRPCRetry {
+	r.retryableCode = retryable
+	return r
+}
+
+// WithAttempts sets the maximal number of attempts of a call, including the first one
+func (r *GRPCRetry) WithAttempts(attempts uint) *GRPCRetry {
+	r.policy.WithAttempts(attempts)
+	return r
+}
+
+// WithBackoff makes the interceptors wait between retries according to given backoff. A longer pushback of the server
+// takes precedence.
+func (r *GRPCRetry) WithBackoff(backoff Backoff) *GRPCRetry {
+	r.policy.WithBackoff(backoff)
+	return r
+}
+
+// WithPolicy makes the interceptors retry calls according to a copy of given policy, replacing retry options and
+// backoff configured so far, so that later changes of either don't affect the other. Calls are retried only if both the
+// status code and the retryable error classifier of the policy allow it. If the retry budget key of the policy is
+// empty, the target of the connection is used as the key. When a retry is refused by the retry budget, the error of the
+// last attempt is returned.
+func (r *GRPCRetry) WithPolicy(policy *Policy) *GRPCRetry {
+	r.policy = policy.clone()
+	return r
+}
+
+// WithMaxPushback caps the delay requested by the server in the grpc-retry-pushback-ms trailer. By default it is
+// DefaultMaxRetryAfter.
+func (r *GRPCRetry) WithMaxPushback(max time.Duration) *GRPCRetry {
+	r.maxPushback = max
+	return r
+}
+
+// UnaryClientInterceptor returns the interceptor retrying unary calls, e.g. for grpc.WithUnaryInterceptor
+func (r *GRPCRetry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return r.do(ctx, cc.Target(), func(trailer *metadata.MD) error {
+			return invoker(ctx, method, req, reply, cc, append(opts[:len(opts):len(opts)], grpc.Trailer(trailer))...)
+		})
+	}
+}
+
+// StreamClientInterceptor returns the interceptor retrying streaming calls, e.g. for grpc.WithStreamInterceptor.
+// Creating the stream is retried for all calls. Server streaming calls are also retried when receiving the first
+// message fails, as the request can be sent again. Client and bidirectional streams are not retried once created.
+func (r *GRPCRetry) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		newStream := func() (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		var stream grpc.ClientStream
+		err := r.do(ctx, cc.Target(), func(*metadata.MD) error {
+			var err error
+			stream, err = newStream()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if desc.ClientStreams {
+			return stream, nil
+		}
+		return &serverStream{stream: stream, ctx: ctx, target: cc.Target(), retry: r, newStream: newStream}, nil
+	}
+}
+
+// do runs the call according to the retry policy. Retrying stops if the status code is not retryable or the server
+// doesn't allow retries in the pushback trailer.
+func (r *GRPCRetry) do(ctx context.Context, target string, call func(trailer *metadata.MD) error) error {
+	var lastErr error
+	run := r.policy.run(ctx, runOptions{
+		key: target,
+		attempt: func(int) attemptResult {
+			var trailer metadata.MD
+			lastErr = call(&trailer)
+			if lastErr == nil {
+				return attemptResult{}
+			}
+			pushback, retryable := r.pushback(trailer)
+			if !retryable || !r.retryableCode(status.Code(lastErr)) {
+				return attemptResult{err: lastErr, decision: stopRetrying}
+			}
+			return attemptResult{err: lastErr, retryAfter: pushback}
+		},
+	})
+
+	switch {
+	case run.retryRefused:
+		return lastErr
+	case run.stopped && run.err == ctx.Err():
+		return status.FromContextError(run.err).Err()
+	default:
+		return run.err
+	}
+}
+
+// pushback returns the delay requested by the server, capped by the maximal pushback. The second return value is false
+// if the server doesn't allow retries.
+func (r *GRPCRetry) pushback(trailer metadata.MD) (time.Duration, bool) {
+	values := trailer.Get(PushbackMetadataKey)
+	if len(values) == 0 {
+		return 0, true
+	}
+	ms, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	pushback := time.Duration(ms) * time.Millisecond
+	if r.maxPushback > 0 && pushback > r.maxPushback {
+		pushback = r.maxPushback
+	}
+	return pushback, true
+}
+
+// serverStream retries a server streaming call if receiving the first message fails. The request message is kept, so
+// that it can be sent on the new stream. Messages may be sent and received by different goroutines, so the current
+// stream and the request are guarded by the mutex.
+type serverStream struct {
+	ctx       context.Context
+	target    string
+	retry     *GRPCRetry
+	newStream func() (grpc.ClientStream, error)
+
+	mu        sync.Mutex
+	stream    grpc.ClientStream
+	request   interface{}
+	closeSent bool
+	received  bool
+}
+
+// current returns the stream of the latest attempt
+func (s *serverStream) current() grpc.ClientStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream
+}
+
+func (s *serverStream) Header() (metadata.MD, error) {
+	return s.current().Header()
+}
+
+func (s *serverStream) Trailer() metadata.MD {
+	return s.current().Trailer()
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.current().Context()
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	s.mu.Lock()
+	s.request = m
+	stream := s.stream
+	s.mu.Unlock()
+	return stream.SendMsg(m)
+}
+
+func (s *serverStream) CloseSend() error {
+	s.mu.Lock()
+	s.closeSent = true
+	stream := s.stream
+	s.mu.Unlock()
+	return stream.CloseSend()
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	s.mu.Lock()
+	stream, received := s.stream, s.received
+	s.mu.Unlock()
+
+	err := stream.RecvMsg(m)
+	if err == nil || received || err == io.EOF {
+		if err == nil && !received {
+			s.mu.Lock()
+			s.received = true
+			s.mu.Unlock()
+		}
+		return err
+	}
+
+	// the failed receive is the first attempt, next ones open a new stream
+	failed, first := err, true
+	err = s.retry.do(s.ctx, s.target, func(trailer *metadata.MD) error {
+		if first {
+			first = false
+			*trailer = stream.Trailer()
+			return failed
+		}
+		stream, err := s.newStream()
+		if err != nil {
+			return err
+		}
+		if err := s.replay(stream); err != nil {
+			return err
+		}
+		if err := stream.RecvMsg(m); err != nil {
+			*trailer = stream.Trailer()
+			return err
+		}
+		return nil
+	})
+	s.mu.Lock()
+	s.received = err == nil
+	s.mu.Unlock()
+	return err
+}
+
+// replay makes the new stream current and sends the request again on it
+func (s *serverStream) replay(stream grpc.ClientStream) error {
+	s.mu.Lock()
+	s.stream = stream
+	request, closeSent := s.request, s.closeSent
+	s.mu.Unlock()
+
+	if request != nil {
+		if err := stream.SendMsg(request); err != nil {
+			return err
+		}
+	}
+	if closeSent {
+		return stream.CloseSend()
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package resilient_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// failingHealthServer fails the first calls with given code and pushback
+type failingHealthServer struct {
+	healthpb.UnimplementedHealthServer
+	calls    int32
+	failures int32
+	code     codes.Code
+	pushback string
+}
+
+func (s *failingHealthServer) fail(ctx context.Context) error {
+	if atomic.AddInt32(&s.calls, 1) > s.failures {
+		return nil
+	}
+	if s.pushback != "" {
+		if err := grpc.SetTrailer(ctx, metadata.Pairs(resilient.PushbackMetadataKey, s.pushback)); err != nil {
+			return err
+		}
+	}
+	return status.Error(s.code, "some failure")
+}
+
+func (s *failingHealthServer) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if err := s.fail(ctx); err != nil {
+		return nil, err
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *failingHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	if err := s.fail(stream.Context()); err != nil {
+		return err
+	}
+	if req.Service != "test" {
+		return status.Error(codes.InvalidArgument, "request was not replayed")
+	}
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+func fixHealthClient(t *testing.T, server *failingHealthServer, grpcRetry *resilient.GRPCRetry) healthpb.HealthClient {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, server)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(grpcRetry.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(grpcRetry.StreamClientInterceptor()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return healthpb.NewHealthClient(conn)
+}
+
+// unavailableStream fails the first receive of all streams sharing the counter with UNAVAILABLE
+type unavailableStream struct {
+	grpc.ClientStream
+	received *int32
+}
+
+func (s *unavailableStream) SendMsg(interface{}) error {
+	return nil
+}
+
+func (s *unavailableStream) CloseSend() error {
+	return nil
+}
+
+func (s *unavailableStream) RecvMsg(interface{}) error {
+	if atomic.AddInt32(s.received, 1) == 1 {
+		return status.Error(codes.Unavailable, "some failure")
+	}
+	return nil
+}
+
+func (s *unavailableStream) Trailer() metadata.MD {
+	return nil
+}
+
+func TestGRPCRetryUnary(t *testing.T) {
+	t.Run("should retry retryable codes", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 2, code: codes.Unavailable}
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+		client := fixHealthClient(t, server, grpcRetry)
+
+		// when
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&server.calls))
+	})
+
+	t.Run("should not retry other codes", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 2, code: codes.InvalidArgument}
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+		client := fixHealthClient(t, server, grpcRetry)
+
+		// when
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+		// then
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&server.calls))
+	})
+
+	t.Run("should return status of the last attempt", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 10, code: codes.ResourceExhausted}
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+		client := fixHealthClient(t, server, grpcRetry)
+
+		// when
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+		// then
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&server.calls))
+	})
+
+	t.Run("should use configured codes", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 1, code: codes.Aborted}
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableCodes(resilient.RetryOnCodes(codes.Aborted))
+		client := fixHealthClient(t, server, grpcRetry)
+
+		// when
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&server.calls))
+	})
+
+	t.Run("should wait for server pushback", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 1, code: codes.Unavailable, pushback: "50"}
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+		client := fixHealthClient(t, server, grpcRetry)
+
+		// when
+		start := time.Now()
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+		// then
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("should not retry when server pushback forbids it", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 1, code: codes.Unavailable, pushback: "-1"}
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+		client := fixHealthClient(t, server, grpcRetry)
+
+		// when
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+		// then
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&server.calls))
+	})
+
+	t.Run("should return status when retry budget of policy is exhausted", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 10, code: codes.Unavailable}
+		budgets := resilient.NewRetryBudgets(resilient.RetryBudgetConfig{
+			Ratio:               0.5,
+			MinRetriesPerSecond: 0.1,
+			Window:              10 * time.Second,
+		})
+		policy := resilient.NewPolicy(retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryBudget(budgets, "")
+		client := fixHealthClient(t, server, resilient.NewGRPCRetry().WithPolicy(policy))
+
+		// when
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+		// then
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&server.calls))
+	})
+
+	t.Run("should stop retrying when context is done", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 10, code: codes.Unavailable}
+		client := fixHealthClient(t, server, resilient.NewGRPCRetry(retry.Attempts(10)).
+			WithBackoff(resilient.Backoff{Type: resilient.FixedBackoff, BaseDelay: time.Second}))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// when
+		start := time.Now()
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+
+		// then
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&server.calls))
+	})
+}
+
+func TestGRPCRetryStream(t *testing.T) {
+	t.Run("should retry server stream until first message is received", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 2, code: codes.Unavailable}
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+		client := fixHealthClient(t, server, grpcRetry)
+
+		// when
+		stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "test"})
+		require.NoError(t, err)
+		resp, err := stream.Recv()
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&server.calls))
+	})
+
+	t.Run("should not retry non-retryable stream errors", func(t *testing.T) {
+		// given
+		server := &failingHealthServer{failures: 2, code: codes.PermissionDenied}
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+		client := fixHealthClient(t, server, grpcRetry)
+
+		// when
+		stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{Service: "test"})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+
+		// then
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&server.calls))
+	})
+
+	t.Run("should allow sending while receiving is retried", func(t *testing.T) {
+		// given
+		conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+		var received int32
+		grpcRetry := resilient.NewGRPCRetry(retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+		interceptor := grpcRetry.StreamClientInterceptor()
+		stream, err := interceptor(context.Background(), &grpc.StreamDesc{ServerStreams: true}, conn, "/test/Watch",
+			func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+				return &unavailableStream{received: &received}, nil
+			})
+		require.NoError(t, err)
+
+		// when
+		sent := make(chan error, 1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				if err := stream.SendMsg(&healthpb.HealthCheckRequest{Service: "test"}); err != nil {
+					sent <- err
+					return
+				}
+			}
+			sent <- stream.CloseSend()
+		}()
+		err = stream.RecvMsg(&healthpb.HealthCheckResponse{})
+
+		// then
+		assert.NoError(t, err)
+		assert.NoError(t, <-sent)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&received))
+	})
+}
@@ -0,0 +1,189 @@
+package resilient
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default values used for zero fields of HedgingConfig
+const (
+	DefaultHedgingDelay = 100 * time.Millisecond
+	DefaultLatencySize  = 100
+)
+
+// hedgingMinSamples is the number of observed latencies required before the percentile replaces the fixed delay
+const hedgingMinSamples = 10
+
+// HedgingConfig configures hedged requests. Zero fields are set to default values.
+type HedgingConfig struct {
+	// Delay after which the hedged attempt is sent. If Percentile is set, it is used until enough latencies are
+	// observed.
+	Delay time.Duration
+	// Percentile of observed latencies of original attempts, e.g. 0.95, used as the delay. The latency of an original
+	// attempt which lost to the hedged one is the time until it was cancelled. It is ignored unless it's between 0
+	// and 1.
+	Percentile float64
+	// LatencySize is the number of recent latencies the percentile is computed from
+	LatencySize int
+	// OnWinner is called with the host and the number of the attempt whose response is used, 1 for the original
+	// attempt and 2 for the hedged one. It must not block.
+	OnWinner func(host string, attempt int)
+}
+
+func (c HedgingConfig) withDefaults() HedgingConfig {
+	if c.Delay <= 0 {
+		c.Delay = DefaultHedgingDelay
+	}
+	if c.Percentile <= 0 || c.Percentile >= 1 {
+		c.Percentile = 0
+	}
+	if c.LatencySize <= 0 {
+		c.LatencySize = DefaultLatencySize
+	}
+	return c
+}
+
+// WithHedging makes the client send a second attempt of idempotent requests if the first one doesn't return within
+// the configured delay. The response which arrives first is used and the other attempt is cancelled. Requests with a
+// body which can't be rewound are never hedged.
+func (c *WrappedHttpClient) WithHedging(config HedgingConfig) *WrappedHttpClient {
+	config = config.withDefaults()
+	c.hedging = &hedging{
+		config:    config,
+		latencies: make([]time.Duration, 0, config.LatencySize),
+	}
+	return c
+}
+
+// hedging computes the hedging delay from recently observed latencies
+type hedging struct {
+	config HedgingConfig
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+func (h *hedging) delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.config.Percentile == 0 || len(h.latencies) < hedgingMinSamples {
+		return h.config.Delay
+	}
+
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(h.config.Percentile*float64(len(sorted)-1))]
+}
+
+func (h *hedging) observe(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < h.config.LatencySize {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % len(h.latencies)
+}
+
+type hedgedResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+// hedgesRequest returns true if attempts of the request may be hedged
+func (c *WrappedHttpClient) hedgesRequest(req *http.Request) bool {
+	return c.hedging != nil && isIdempotent(req.Method)
+}
+
+// hedgedAttempt sends an attempt of the request and, if it is slower than the hedging delay, another one. The first
+// response is returned and the other attempt is cancelled. If the first attempt fails before the delay, its error is
+// returned without hedging, so that it is handled by the retry loop.
+func (c *WrappedHttpClient) hedgedAttempt(req *http.Request, rewinder *bodyRewinder) (*http.Response, error) {
+	if !c.hedgesRequest(req) || !rewinder.rewindable() {
+		return c.attempt(req)
+	}
+
+	results := make(chan hedgedResult, 2)
+	cancels := make(map[int]context.CancelFunc, 2)
+	send := func(attempt int, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels[attempt] = cancel
+		go func() {
+			resp, err := c.attempt(req.WithContext(ctx))
+			results <- hedgedResult{attempt: attempt, resp: resp, err: err}
+		}()
+	}
+
+	start := time.Now()
+	send(1, req)
+	timer := time.NewTimer(c.hedging.delay())
+	defer timer.Stop()
+	hedge := timer.C
+	pending := 1
+
+	var last hedgedResult
+	for pending > 0 {
+		select {
+		case <-hedge:
+			hedge = nil
+			hedgedReq, err := rewinder.request(req)
+			if err != nil {
+				continue
+			}
+			send(2, hedgedReq)
+			pending++
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				// the latency of the original attempt is observed, also when it loses and is cancelled, so that the
+				// delay doesn't shrink to the latency of hedged attempts
+				c.hedging.observe(time.Since(start))
+				c.useHedgedResult(req, result, cancels, results, pending)
+				return result.resp, nil
+			}
+			cancels[result.attempt]()
+			last = result
+			if hedge != nil {
+				return last.resp, last.err
+			}
+		}
+	}
+	return last.resp, last.err
+}
+
+// useHedgedResult records the winning attempt and cancels the others. Responses of cancelled attempts which still
+// arrive are discarded.
+func (c *WrappedHttpClient) useHedgedResult(req *http.Request, winner hedgedResult, cancels map[int]context.CancelFunc,
+	results <-chan hedgedResult, pending int) {
+	if c.hedging.config.OnWinner != nil {
+		c.hedging.config.OnWinner(req.URL.Host, winner.attempt)
+	}
+
+	for attempt, cancel := range cancels {
+		if attempt != winner.attempt {
+			cancel()
+		}
+	}
+	if pending > 0 {
+		go func() {
+			for i := 0; i < pending; i++ {
+				discardResponse((<-results).resp)
+			}
+		}()
+	}
+
+	cancel := cancels[winner.attempt]
+	if winner.resp == nil || winner.resp.Body == nil {
+		cancel()
+		return
+	}
+	// the response body is read after the attempt returns, so its context is cancelled when it gets closed
+	winner.resp.Body = onClose(winner.resp.Body, cancel)
+}
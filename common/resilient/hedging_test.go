@@ -0,0 +1,167 @@
+package resilient_test
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delayedHttpClient delays responses to calls listed in slowCalls until the request context is done
+type delayedHttpClient struct {
+	calls     int32
+	slowCalls map[int32]bool
+	cancelled int32
+}
+
+func (c *delayedHttpClient) Do(req *http.Request) (*http.Response, error) {
+	call := atomic.AddInt32(&c.calls, 1)
+	if c.slowCalls[call] {
+		select {
+		case <-req.Context().Done():
+			atomic.AddInt32(&c.cancelled, 1)
+			return nil, req.Context().Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Call": {strconv.Itoa(int(call))}},
+		Body:       io.NopCloser(strings.NewReader("body")),
+	}, nil
+}
+
+type winnerRecorder struct {
+	mu      sync.Mutex
+	winners []int
+}
+
+func (r *winnerRecorder) record(_ string, attempt int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.winners = append(r.winners, attempt)
+}
+
+func TestHttpClientHedging(t *testing.T) {
+	t.Run("should use hedged attempt when the first one is slow", func(t *testing.T) {
+		// given
+		mock := &delayedHttpClient{slowCalls: map[int32]bool{1: true}}
+		recorder := &winnerRecorder{}
+		wrapped := resilient.WrapHttpClient(mock).WithHedging(resilient.HedgingConfig{
+			Delay:    10 * time.Millisecond,
+			OnWinner: recorder.record,
+		})
+
+		// when
+		start := time.Now()
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, "2", resp.Header.Get("Call"))
+		assert.Equal(t, []int{2}, recorder.winners)
+		assert.Eventually(t, func() bool {
+			return atomic.LoadInt32(&mock.cancelled) == 1
+		}, time.Second, time.Millisecond, "slower attempt should be cancelled")
+	})
+
+	t.Run("should not hedge fast requests", func(t *testing.T) {
+		// given
+		mock := &delayedHttpClient{}
+		recorder := &winnerRecorder{}
+		wrapped := resilient.WrapHttpClient(mock).WithHedging(resilient.HedgingConfig{
+			Delay:    50 * time.Millisecond,
+			OnWinner: recorder.record,
+		})
+
+		// when
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&mock.calls))
+		assert.Equal(t, []int{1}, recorder.winners)
+	})
+
+	t.Run("should not hedge non-idempotent requests", func(t *testing.T) {
+		// given
+		mock := &delayedHttpClient{slowCalls: map[int32]bool{1: true}}
+		wrapped := resilient.WrapHttpClient(mock).WithHedging(resilient.HedgingConfig{Delay: time.Millisecond})
+
+		// when
+		resp, err := wrapped.Post("http://example.com/", "text/plain", strings.NewReader("body"))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "1", resp.Header.Get("Call"))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&mock.calls))
+	})
+
+	t.Run("should use percentile of observed latencies as delay", func(t *testing.T) {
+		// given
+		mock := &delayedHttpClient{slowCalls: map[int32]bool{11: true}}
+		recorder := &winnerRecorder{}
+		wrapped := resilient.WrapHttpClient(mock).WithHedging(resilient.HedgingConfig{
+			Delay:      time.Minute,
+			Percentile: 0.9,
+			OnWinner:   recorder.record,
+		})
+		for i := 0; i < 10; i++ {
+			resp, err := wrapped.Get("http://example.com/")
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+		}
+
+		// when
+		start := time.Now()
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, recorder.winners[len(recorder.winners)-1])
+	})
+
+	t.Run("should not shrink delay when original attempts are slow", func(t *testing.T) {
+		// given
+		slowCalls := map[int32]bool{}
+		for call := int32(1); call < 100; call += 2 {
+			slowCalls[call] = true
+		}
+		mock := &delayedHttpClient{slowCalls: slowCalls}
+		recorder := &winnerRecorder{}
+		wrapped := resilient.WrapHttpClient(mock).WithHedging(resilient.HedgingConfig{
+			Delay:      20 * time.Millisecond,
+			Percentile: 0.5,
+			OnWinner:   recorder.record,
+		})
+		for i := 0; i < 10; i++ {
+			resp, err := wrapped.Get("http://example.com/")
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+		}
+
+		// when
+		start := time.Now()
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+		assert.Equal(t, "22", resp.Header.Get("Call"))
+		assert.Equal(t, 2, recorder.winners[len(recorder.winners)-1])
+	})
+}
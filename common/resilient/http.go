@@ -1,10 +1,12 @@
 package resilient
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	retry "github.com/avast/retry-go"
 )
@@ -12,8 +14,24 @@ import (
 // WrappedHttpClient is a wrapper around HttpClient which retries requests in case of errors. It implements all of public
 // methods available in http.Client
 type WrappedHttpClient struct {
-	underlying HttpClient
-	opts       []retry.Option
+	underlying       HttpClient
+	policy           *Policy
+	tracePropagation TracePropagation
+	traceFromContext TraceFromContextFunc
+	retryableStatus  RetryableStatusFunc
+
+	maxBufferedBodySize int64
+	maxRetryAfter       time.Duration
+	attemptTimeout      time.Duration
+	limits              *limiters
+	hedging             *hedging
+
+	retryableRequest        RetryableRequestFunc
+	generateIdempotencyKeys bool
+	observers               []Observer
+	bulkhead                *Bulkhead
+	fallback                FallbackFunc
+	staleCache              *staleCache
 }
 
 // HttpClient is a simplified version of http.Client interface
@@ -27,55 +45,234 @@ func NewHttpClient(opts ...retry.Option) *WrappedHttpClient {
 	return WrapHttpClient(&http.Client{}, opts...)
 }
 
-// WrapHttpClient returns new WrappedHttpClient with given HttpClient as upstream
+// WrapHttpClient returns new WrappedHttpClient with given HttpClient as upstream. Delays between retries are computed
+// by the backoff, see WithBackoff, so Delay and DelayType options have no effect.
 func WrapHttpClient(client HttpClient, opts ...retry.Option) *WrappedHttpClient {
 	return &WrappedHttpClient{
-		underlying: client,
-		opts:       opts,
+		underlying:          client,
+		policy:              newPolicy(opts),
+		maxBufferedBodySize: DefaultMaxBufferedBodySize,
+		maxRetryAfter:       DefaultMaxRetryAfter,
 	}
 }
 
-// Do calls Do method of underlying HttpClient and retries according to given options when an error occurs
-func (c *WrappedHttpClient) Do(req *http.Request) (resp *http.Response, err error) {
-	err = retry.Do(func() error {
-		resp, err = c.underlying.Do(req)
-		if err != nil {
-			return err
-		}
-		return nil
-	}, c.opts...)
-	return resp, err
+// WithAttempts sets the maximal number of attempts of a request, including the first one. By default it is set by
+// retry.Attempts or, if there is none, it is DefaultAttempts.
+func (c *WrappedHttpClient) WithAttempts(attempts uint) *WrappedHttpClient {
+	c.policy.WithAttempts(attempts)
+	return c
 }
 
-// Get is copied from http.Client to be compliant with its interface. For more documentation see http.Client.Get
-func (c *WrappedHttpClient) Get(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// WithPolicy makes the client retry requests according to a copy of given policy, replacing retry options, backoff,
+// retryable error classifier, circuit breakers and retry budgets configured so far. The same policy can be used with
+// Execute and GRPCRetry. Later changes of the client don't affect the policy and vice versa, while its circuit breaker
+// and retry budgets stay shared. Its circuit breaker and retry budget key, if set, are shared by all hosts.
+func (c *WrappedHttpClient) WithPolicy(policy *Policy) *WrappedHttpClient {
+	c.policy = policy.clone()
+	return c
+}
+
+// Do calls Do method of underlying HttpClient and retries according to given options when a transient error occurs,
+// see WithRetryableError, or, if configured with WithRetryableStatus, when a retryable status code is received.
+// Retry-After headers of retryable responses are respected, see WithMaxRetryAfter. The request body is rewound for
+// every attempt, see WithMaxBufferedBodySize. Only idempotent requests are retried, see WithRetryableRequest. Retries
+// stop as soon as the request context is done and its error is returned.
+func (c *WrappedHttpClient) Do(req *http.Request) (*http.Response, error) {
+	req = c.injectTraceHeaders(req)
+	req, err := c.injectIdempotencyKey(req)
 	if err != nil {
 		return nil, err
 	}
-	return c.Do(req)
+	rewinder, err := newBodyRewinder(req, c.maxBufferedBodySize, c.retriesRequest(req) || c.hedgesRequest(req))
+	if err != nil {
+		return nil, err
+	}
+
+	return untilDone(req.Context(), func() (*http.Response, error) {
+		return c.withFallback(req, rewinder)
+	}, discardResponse)
 }
 
-// Post is copied from http.Client to be compliant with its interface. For more documentation see http.Client.Post
-func (c *WrappedHttpClient) Post(url, contentType string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest("POST", url, body)
+// retry runs attempts of the request according to the retry policy. The circuit breaker and the retry budget of the
+// request host are used.
+func (c *WrappedHttpClient) retry(req *http.Request, rewinder *bodyRewinder) (*http.Response, error) {
+	var resp, retryableResp *http.Response
+	var lastErr error
+	var lastEnd time.Time
+	start := time.Now()
+	retryable := c.retriesRequest(req)
+	run := c.policy.run(req.Context(), runOptions{
+		key:  req.URL.Host,
+		once: !rewinder.rewindable() || !retryable,
+		retrying: func() {
+			discardResponse(retryableResp)
+			retryableResp = nil
+		},
+		attempt: func(number int) attemptResult {
+			attemptReq, err := rewinder.request(req)
+			if err != nil {
+				lastErr = err
+				return attemptResult{err: err}
+			}
+			attemptStart := time.Now()
+			var result attemptResult
+			resp, result.err = c.hedgedAttempt(attemptReq, rewinder)
+			attempt := Attempt{Request: req, Number: number, Duration: time.Since(attemptStart), StatusCode: statusCode(resp)}
+			if number > 1 {
+				attempt.Delay = attemptStart.Sub(lastEnd)
+			}
+			lastEnd = time.Now()
+
+			if result.err == nil && resp != nil {
+				result.failed = c.isFailureStatus(resp.StatusCode)
+				if statusErr := c.checkStatus(resp); statusErr != nil {
+					retryableResp = resp
+					result.err, result.retryAfter, result.decision = statusErr, statusErr.RetryAfter, retryError
+				}
+			}
+			lastErr = result.err
+			attempt.Err = unmarkPermanent(result.err)
+			c.observeAttempt(attempt)
+			return result
+		},
+	})
+
+	result := Result{Request: req, Attempts: run.attempts, Duration: time.Since(start), RetryRefused: run.retryRefused}
+	var err error
+	switch {
+	case run.err == nil:
+	case run.stopped:
+		discardResponse(retryableResp)
+		resp, err = nil, run.err
+	case !rewinder.rewindable() && retryable:
+		// a retry was needed, but the body was sent once already
+		discardResponse(retryableResp)
+		resp, err = nil, withCause(ErrBodyNotRewindable, lastErr)
+	case retryableResp != nil:
+		resp = retryableResp
+		result.GaveUp = true
+	default:
+		resp, err = nil, run.err
+	}
+	result.StatusCode, result.Err = statusCode(resp), err
+	result.GaveUp = result.GaveUp || err != nil
+	c.observeResult(result)
+	return resp, err
+}
+
+// retriesRequest returns true if the request may be sent again by a retry
+func (c *WrappedHttpClient) retriesRequest(req *http.Request) bool {
+	return c.isRetryableRequest(req) && c.policy.retries()
+}
+
+// attempt sends a single attempt of the request once it is allowed by limits
+func (c *WrappedHttpClient) attempt(req *http.Request) (*http.Response, error) {
+	release, err := c.acquireLimits(req)
 	if err != nil {
-		return nil, err
+		return nil, Permanent(err)
 	}
-	req.Header.Set("Content-Type", contentType)
-	return c.Do(req)
+	resp, err := c.doWithTimeout(req)
+	if err != nil || resp == nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+	resp.Body = onClose(resp.Body, release)
+	return resp, nil
+}
+
+// DoWithContext sends the request with given context. For more documentation see Do
+func (c *WrappedHttpClient) DoWithContext(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return c.Do(req.WithContext(ctx))
+}
+
+// Get is copied from http.Client to be compliant with its interface. For more documentation see http.Client.Get
+func (c *WrappedHttpClient) Get(url string) (*http.Response, error) {
+	return c.GetWithContext(context.Background(), url)
+}
+
+// GetWithContext issues a GET to the specified URL with given context
+func (c *WrappedHttpClient) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, http.MethodGet, url, "", nil)
+}
+
+// Post is copied from http.Client to be compliant with its interface. For more documentation see http.Client.Post
+func (c *WrappedHttpClient) Post(url, contentType string, body io.Reader) (*http.Response, error) {
+	return c.PostWithContext(context.Background(), url, contentType, body)
+}
+
+// PostWithContext issues a POST to the specified URL with given context
+func (c *WrappedHttpClient) PostWithContext(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	return c.send(ctx, http.MethodPost, url, contentType, body)
 }
 
 // PostForm is copied from http.Client to be compliant with its interface. For more documentation see http.Client.PostForm
 func (c *WrappedHttpClient) PostForm(url string, data url.Values) (*http.Response, error) {
-	return c.Post(url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	return c.PostFormWithContext(context.Background(), url, data)
+}
+
+// PostFormWithContext issues a POST with URL-encoded data to the specified URL with given context
+func (c *WrappedHttpClient) PostFormWithContext(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.PostWithContext(ctx, url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
 }
 
 // Head is copied from http.Client to be compliant with its interface. For more documentation see http.Client.Head
 func (c *WrappedHttpClient) Head(url string) (*http.Response, error) {
-	req, err := http.NewRequest("HEAD", url, nil)
+	return c.HeadWithContext(context.Background(), url)
+}
+
+// HeadWithContext issues a HEAD to the specified URL with given context
+func (c *WrappedHttpClient) HeadWithContext(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, http.MethodHead, url, "", nil)
+}
+
+// Put issues a PUT to the specified URL
+func (c *WrappedHttpClient) Put(url, contentType string, body io.Reader) (*http.Response, error) {
+	return c.PutWithContext(context.Background(), url, contentType, body)
+}
+
+// PutWithContext issues a PUT to the specified URL with given context
+func (c *WrappedHttpClient) PutWithContext(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	return c.send(ctx, http.MethodPut, url, contentType, body)
+}
+
+// Patch issues a PATCH to the specified URL
+func (c *WrappedHttpClient) Patch(url, contentType string, body io.Reader) (*http.Response, error) {
+	return c.PatchWithContext(context.Background(), url, contentType, body)
+}
+
+// PatchWithContext issues a PATCH to the specified URL with given context
+func (c *WrappedHttpClient) PatchWithContext(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	return c.send(ctx, http.MethodPatch, url, contentType, body)
+}
+
+// Delete issues a DELETE to the specified URL
+func (c *WrappedHttpClient) Delete(url string) (*http.Response, error) {
+	return c.DeleteWithContext(context.Background(), url)
+}
+
+// DeleteWithContext issues a DELETE to the specified URL with given context
+func (c *WrappedHttpClient) DeleteWithContext(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, http.MethodDelete, url, "", nil)
+}
+
+// Options issues an OPTIONS to the specified URL
+func (c *WrappedHttpClient) Options(url string) (*http.Response, error) {
+	return c.OptionsWithContext(context.Background(), url)
+}
+
+// OptionsWithContext issues an OPTIONS to the specified URL with given context
+func (c *WrappedHttpClient) OptionsWithContext(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, http.MethodOptions, url, "", nil)
+}
+
+// send creates the request and sends it with Do. The Content-Type header is set only if contentType is not empty.
+func (c *WrappedHttpClient) send(ctx context.Context, method, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 	return c.Do(req)
 }
@@ -1,8 +1,11 @@
 package resilient_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 
 	retry "github.com/avast/retry-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockHttpClient struct {
@@ -25,10 +29,15 @@ func (c *mockHttpClient) Do(req *http.Request) (*http.Response, error) {
 	return nil, errors.New("some connection error")
 }
 
+// fixedBackoff returns the backoff which waits the same delay before every retry
+func fixedBackoff(delay time.Duration) resilient.Backoff {
+	return resilient.Backoff{Type: resilient.FixedBackoff, BaseDelay: delay}
+}
+
 func TestHttpClientFirstSuccess(t *testing.T) {
 	// given
 	mock := &mockHttpClient{successAfter: 1}
-	wrapped := resilient.WrapHttpClient(mock, retry.Delay(time.Millisecond), retry.Attempts(5))
+	wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
 
 	// when
 	resp, err := wrapped.Get("http://example.com/")
@@ -42,7 +51,7 @@ func TestHttpClientFirstSuccess(t *testing.T) {
 func TestHttpClientLateSuccess(t *testing.T) {
 	// given
 	mock := &mockHttpClient{successAfter: 3}
-	wrapped := resilient.WrapHttpClient(mock, retry.Delay(time.Millisecond), retry.Attempts(5))
+	wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
 
 	// when
 	resp, err := wrapped.Get("http://example.com/")
@@ -56,7 +65,7 @@ func TestHttpClientLateSuccess(t *testing.T) {
 func TestHttpClientError(t *testing.T) {
 	// given
 	mock := &mockHttpClient{successAfter: 10}
-	wrapped := resilient.WrapHttpClient(mock, retry.Delay(time.Millisecond), retry.Attempts(5))
+	wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
 
 	// when
 	_, err := wrapped.Get("http://example.com/")
@@ -65,3 +74,106 @@ func TestHttpClientError(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, 5, mock.calls)
 }
+
+type contextKey struct{}
+
+func TestHttpClientMethods(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+
+	for name, tc := range map[string]struct {
+		send                func(c *resilient.WrappedHttpClient) (*http.Response, error)
+		expectedMethod      string
+		expectedContentType string
+		withContext         bool
+	}{
+		"Get": {
+			send:           func(c *resilient.WrappedHttpClient) (*http.Response, error) { return c.Get("http://example.com/") },
+			expectedMethod: http.MethodGet,
+		},
+		"GetWithContext": {
+			send: func(c *resilient.WrappedHttpClient) (*http.Response, error) {
+				return c.GetWithContext(ctx, "http://example.com/")
+			},
+			expectedMethod: http.MethodGet,
+			withContext:    true,
+		},
+		"PostWithContext": {
+			send: func(c *resilient.WrappedHttpClient) (*http.Response, error) {
+				return c.PostWithContext(ctx, "http://example.com/", "text/plain", strings.NewReader("body"))
+			},
+			expectedMethod:      http.MethodPost,
+			expectedContentType: "text/plain",
+			withContext:         true,
+		},
+		"PostFormWithContext": {
+			send: func(c *resilient.WrappedHttpClient) (*http.Response, error) {
+				return c.PostFormWithContext(ctx, "http://example.com/", url.Values{"key": {"value"}})
+			},
+			expectedMethod:      http.MethodPost,
+			expectedContentType: "application/x-www-form-urlencoded",
+			withContext:         true,
+		},
+		"HeadWithContext": {
+			send: func(c *resilient.WrappedHttpClient) (*http.Response, error) {
+				return c.HeadWithContext(ctx, "http://example.com/")
+			},
+			expectedMethod: http.MethodHead,
+			withContext:    true,
+		},
+		"Put": {
+			send: func(c *resilient.WrappedHttpClient) (*http.Response, error) {
+				return c.Put("http://example.com/", "text/plain", strings.NewReader("body"))
+			},
+			expectedMethod:      http.MethodPut,
+			expectedContentType: "text/plain",
+		},
+		"PatchWithContext": {
+			send: func(c *resilient.WrappedHttpClient) (*http.Response, error) {
+				return c.PatchWithContext(ctx, "http://example.com/", "text/plain", strings.NewReader("body"))
+			},
+			expectedMethod:      http.MethodPatch,
+			expectedContentType: "text/plain",
+			withContext:         true,
+		},
+		"Delete": {
+			send:           func(c *resilient.WrappedHttpClient) (*http.Response, error) { return c.Delete("http://example.com/") },
+			expectedMethod: http.MethodDelete,
+		},
+		"OptionsWithContext": {
+			send: func(c *resilient.WrappedHttpClient) (*http.Response, error) {
+				return c.OptionsWithContext(ctx, "http://example.com/")
+			},
+			expectedMethod: http.MethodOptions,
+			withContext:    true,
+		},
+		"DoWithContext": {
+			send: func(c *resilient.WrappedHttpClient) (*http.Response, error) {
+				req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+				require.NoError(t, err)
+				return c.DoWithContext(ctx, req)
+			},
+			expectedMethod: http.MethodGet,
+			withContext:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// given
+			mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 1}}
+			wrapped := resilient.WrapHttpClient(mock)
+
+			// when
+			resp, err := tc.send(wrapped)
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+			require.Len(t, mock.requests, 1)
+			sent := mock.requests[0]
+			assert.Equal(t, tc.expectedMethod, sent.Method)
+			assert.Equal(t, tc.expectedContentType, sent.Header.Get("Content-Type"))
+			if tc.withContext {
+				assert.Equal(t, "value", sent.Context().Value(contextKey{}))
+			}
+		})
+	}
+}
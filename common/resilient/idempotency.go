@@ -0,0 +1,92 @@
+package resilient
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is the header which makes a POST request safe to retry. The server is expected to process
+// requests with the same key only once.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryableRequestFunc decides whether a failed request may be sent again
+type RetryableRequestFunc func(req *http.Request) bool
+
+// idempotentMethods are methods which can be safely sent more than once, see RFC 7231 section 4.2.2
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+func isIdempotent(method string) bool {
+	if method == "" {
+		method = http.MethodGet
+	}
+	return idempotentMethods[method]
+}
+
+// DefaultRetryableRequest retries requests with idempotent methods and POST requests with the Idempotency-Key header
+func DefaultRetryableRequest(req *http.Request) bool {
+	if isIdempotent(req.Method) {
+		return true
+	}
+	return req.Method == http.MethodPost && req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// RetryAllRequests retries requests regardless of their method. It may duplicate side effects of requests which are
+// not idempotent.
+func RetryAllRequests(*http.Request) bool {
+	return true
+}
+
+// WithRetryableRequest sets the function which decides whether a request may be retried. Requests which are not
+// retryable are sent once. By default DefaultRetryableRequest is used.
+func (c *WrappedHttpClient) WithRetryableRequest(fn RetryableRequestFunc) *WrappedHttpClient {
+	c.retryableRequest = fn
+	return c
+}
+
+// WithIdempotencyKeys makes the client set a random Idempotency-Key header on POST requests without one, so that they
+// can be retried. The same key is sent on every attempt.
+func (c *WrappedHttpClient) WithIdempotencyKeys() *WrappedHttpClient {
+	c.generateIdempotencyKeys = true
+	return c
+}
+
+func (c *WrappedHttpClient) isRetryableRequest(req *http.Request) bool {
+	if c.retryableRequest == nil {
+		return DefaultRetryableRequest(req)
+	}
+	return c.retryableRequest(req)
+}
+
+// injectIdempotencyKey returns a copy of the request with a generated Idempotency-Key header. The original request is
+// returned if the key is not needed.
+func (c *WrappedHttpClient) injectIdempotencyKey(req *http.Request) (*http.Request, error) {
+	if !c.generateIdempotencyKeys || req.Method != http.MethodPost || req.Header.Get(IdempotencyKeyHeader) != "" {
+		return req, nil
+	}
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return nil, fmt.Errorf("while generating idempotency key: %w", err)
+	}
+
+	out := req.Clone(req.Context())
+	out.Header.Set(IdempotencyKeyHeader, key)
+	return out, nil
+}
+
+// newIdempotencyKey returns a random UUID version 4
+func newIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,115 @@
+package resilient_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpClientIdempotency(t *testing.T) {
+	t.Run("should not retry POST without idempotency key", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 2}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+
+		// when
+		_, err := wrapped.Post("http://example.com/", "text/plain", strings.NewReader("payload"))
+
+		// then
+		assert.Error(t, err)
+		assert.Len(t, mock.requests, 1)
+	})
+
+	t.Run("should not retry PATCH", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 2}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+		req, err := http.NewRequest(http.MethodPatch, "http://example.com/", strings.NewReader("payload"))
+		require.NoError(t, err)
+		req.Header.Set(resilient.IdempotencyKeyHeader, "key")
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		assert.Error(t, err)
+		assert.Len(t, mock.requests, 1)
+	})
+
+	t.Run("should retry POST with idempotency key", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 3}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond))
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("payload"))
+		require.NoError(t, err)
+		req.Header.Set(resilient.IdempotencyKeyHeader, "key")
+
+		// when
+		resp, err := wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+		assert.Len(t, mock.requests, 3)
+	})
+
+	t.Run("should generate the same idempotency key for every attempt", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 3}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithIdempotencyKeys()
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("payload"))
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, mock.requests, 3)
+		key := mock.requests[0].Header.Get(resilient.IdempotencyKeyHeader)
+		assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", key)
+		for _, sent := range mock.requests {
+			assert.Equal(t, key, sent.Header.Get(resilient.IdempotencyKeyHeader))
+		}
+		assert.Empty(t, req.Header, "original request should not be modified")
+	})
+
+	t.Run("should not override idempotency key set by the caller", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 1}}
+		wrapped := resilient.WrapHttpClient(mock).WithIdempotencyKeys()
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("payload"))
+		require.NoError(t, err)
+		req.Header.Set(resilient.IdempotencyKeyHeader, "key")
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "key", mock.requests[0].Header.Get(resilient.IdempotencyKeyHeader))
+	})
+
+	t.Run("should retry all requests with custom policy", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 2}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableRequest(resilient.RetryAllRequests)
+
+		// when
+		resp, err := wrapped.Post("http://example.com/", "text/plain", strings.NewReader("payload"))
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+		assert.Len(t, mock.requests, 2)
+	})
+}
@@ -0,0 +1,94 @@
+package resilient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const jsonContentType = "application/json"
+
+// maxErrorBodyBytes limits how much of the body of an unexpected response is kept in ResponseError
+const maxErrorBodyBytes = 4 << 10
+
+// ResponseError is returned by JSON helpers when the response has a non-2xx status code
+type ResponseError struct {
+	StatusCode int
+	// Body holds the beginning of the response body
+	Body []byte
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// GetJSON issues a GET to the specified URL and decodes the JSON response into out. See DoJSON for details.
+func (c *WrappedHttpClient) GetJSON(ctx context.Context, url string, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodGet, url, nil, out)
+}
+
+// PostJSON issues a POST of in encoded as JSON to the specified URL and decodes the JSON response into out. See DoJSON
+// for details.
+func (c *WrappedHttpClient) PostJSON(ctx context.Context, url string, in, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPost, url, in, out)
+}
+
+// PutJSON issues a PUT of in encoded as JSON to the specified URL and decodes the JSON response into out. See DoJSON
+// for details.
+func (c *WrappedHttpClient) PutJSON(ctx context.Context, url string, in, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPut, url, in, out)
+}
+
+// PatchJSON issues a PATCH of in encoded as JSON to the specified URL and decodes the JSON response into out. See
+// DoJSON for details.
+func (c *WrappedHttpClient) PatchJSON(ctx context.Context, url string, in, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPatch, url, in, out)
+}
+
+// DeleteJSON issues a DELETE to the specified URL and decodes the JSON response into out. See DoJSON for details.
+func (c *WrappedHttpClient) DeleteJSON(ctx context.Context, url string, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodDelete, url, nil, out)
+}
+
+// DoJSON sends a request with in encoded as JSON, unless it is nil, and decodes the JSON response into out, unless it
+// is nil or the response has no content. A response with a non-2xx status code is returned as *ResponseError.
+func (c *WrappedHttpClient) DoJSON(ctx context.Context, method, url string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("while encoding request body: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", jsonContentType)
+	if in != nil {
+		req.Header.Set("Content-Type", jsonContentType)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer discardResponse(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return &ResponseError{StatusCode: resp.StatusCode, Body: errBody}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("while decoding response body: %w", err)
+	}
+	return nil
+}
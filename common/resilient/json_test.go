@@ -0,0 +1,118 @@
+package resilient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	Name string `json:"name"`
+}
+
+func TestHttpClientJSON(t *testing.T) {
+	t.Run("should encode request and decode response", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, "application/json", r.Header.Get("Accept"))
+			var in item
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(item{Name: in.Name + "-created"})
+		}))
+		defer server.Close()
+		wrapped := resilient.NewHttpClient()
+
+		// when
+		var out item
+		err := wrapped.PostJSON(context.Background(), server.URL, item{Name: "test"}, &out)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "test-created", out.Name)
+	})
+
+	t.Run("should decode response of GET", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			assert.Empty(t, r.Header.Get("Content-Type"))
+			_, _ = io.WriteString(w, `[{"name":"first"},{"name":"second"}]`)
+		}))
+		defer server.Close()
+		wrapped := resilient.NewHttpClient()
+
+		// when
+		var out []item
+		err := wrapped.GetJSON(context.Background(), server.URL, &out)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []item{{Name: "first"}, {Name: "second"}}, out)
+	})
+
+	t.Run("should accept empty response", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodDelete, r.Method)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		wrapped := resilient.NewHttpClient()
+
+		// when
+		var out item
+		err := wrapped.DeleteJSON(context.Background(), server.URL, &out)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, item{}, out)
+	})
+
+	t.Run("should return ResponseError for non-2xx status code", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":"not found"}`)
+		}))
+		defer server.Close()
+		wrapped := resilient.NewHttpClient()
+
+		// when
+		var out item
+		err := wrapped.PutJSON(context.Background(), server.URL, item{Name: "test"}, &out)
+
+		// then
+		var responseErr *resilient.ResponseError
+		require.True(t, errors.As(err, &responseErr))
+		assert.Equal(t, http.StatusNotFound, responseErr.StatusCode)
+		assert.JSONEq(t, `{"error":"not found"}`, string(responseErr.Body))
+	})
+
+	t.Run("should return error for invalid response", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "not json")
+		}))
+		defer server.Close()
+		wrapped := resilient.NewHttpClient()
+
+		// when
+		var out item
+		err := wrapped.PatchJSON(context.Background(), server.URL, item{Name: "test"}, &out)
+
+		// then
+		assert.Error(t, err)
+	})
+}
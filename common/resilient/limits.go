@@ -0,0 +1,157 @@
+package resilient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Limits configures client-side rate limiting and concurrency caps. Zero fields mean no limit.
+type Limits struct {
+	// RequestsPerSecond is the rate at which tokens are added to the token bucket
+	RequestsPerSecond float64
+	// Burst is the size of the token bucket. It defaults to 1 if RequestsPerSecond is set.
+	Burst int
+	// MaxInFlight limits the number of concurrent requests. A request is in flight until its response body is closed.
+	MaxInFlight int
+}
+
+// WithLimits sets limits shared by requests to all hosts. Limits are applied to every attempt, including retries, and
+// waiting for them respects the request context.
+func (c *WrappedHttpClient) WithLimits(global Limits) *WrappedHttpClient {
+	c.limiters().global = newLimiter(global)
+	return c
+}
+
+// WithHostLimits sets limits applied separately to every host. Limits of specific hosts can be overridden with
+// WithLimitsForHost.
+func (c *WrappedHttpClient) WithHostLimits(perHost Limits) *WrappedHttpClient {
+	c.limiters().setHostDefaults(perHost)
+	return c
+}
+
+// WithLimitsForHost sets limits of given host, overriding limits set by WithHostLimits
+func (c *WrappedHttpClient) WithLimitsForHost(host string, limits Limits) *WrappedHttpClient {
+	c.limiters().setHost(host, limits)
+	return c
+}
+
+func (c *WrappedHttpClient) limiters() *limiters {
+	if c.limits == nil {
+		c.limits = &limiters{
+			byHost:    map[string]*limiter{},
+			overrides: map[string]bool{},
+		}
+	}
+	return c.limits
+}
+
+// acquireLimits waits until the request is allowed by global and host limits. The returned function releases the
+// in-flight slots.
+func (c *WrappedHttpClient) acquireLimits(req *http.Request) (func(), error) {
+	if c.limits == nil {
+		return func() {}, nil
+	}
+	return c.limits.acquire(req.Context(), req.URL.Host)
+}
+
+// limiter combines a token bucket with a semaphore limiting the number of requests in flight
+type limiter struct {
+	rate     *rate.Limiter
+	inFlight chan struct{}
+}
+
+func newLimiter(limits Limits) *limiter {
+	l := &limiter{}
+	if limits.RequestsPerSecond > 0 {
+		burst := limits.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		l.rate = rate.NewLimiter(rate.Limit(limits.RequestsPerSecond), burst)
+	}
+	if limits.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limits.MaxInFlight)
+	}
+	return l
+}
+
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// limiters holds the global limiter and a limiter per host
+type limiters struct {
+	global *limiter
+
+	mu           sync.Mutex
+	hostDefaults *Limits
+	byHost       map[string]*limiter
+	overrides    map[string]bool
+}
+
+func (l *limiters) setHostDefaults(limits Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hostDefaults = &limits
+	for host := range l.byHost {
+		if !l.overrides[host] {
+			delete(l.byHost, host)
+		}
+	}
+}
+
+func (l *limiters) setHost(host string, limits Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.byHost[host] = newLimiter(limits)
+	l.overrides[host] = true
+}
+
+func (l *limiters) host(host string) *limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if hostLimiter, ok := l.byHost[host]; ok {
+		return hostLimiter
+	}
+	if l.hostDefaults == nil {
+		return nil
+	}
+	hostLimiter := newLimiter(*l.hostDefaults)
+	l.byHost[host] = hostLimiter
+	return hostLimiter
+}
+
+func (l *limiters) acquire(ctx context.Context, host string) (func(), error) {
+	releaseGlobal, err := l.global.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	releaseHost, err := l.host(host).acquire(ctx)
+	if err != nil {
+		releaseGlobal()
+		return nil, err
+	}
+	return func() {
+		releaseHost()
+		releaseGlobal()
+	}, nil
+}
@@ -0,0 +1,121 @@
+package resilient_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingHttpClient struct {
+	calls        int32
+	successAfter int32
+}
+
+func (c *countingHttpClient) Do(req *http.Request) (*http.Response, error) {
+	if atomic.AddInt32(&c.calls, 1) < c.successAfter {
+		return nil, errors.New("some connection error")
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("body"))}, nil
+}
+
+func TestHttpClientLimits(t *testing.T) {
+	t.Run("should wait for in-flight request until its body is closed", func(t *testing.T) {
+		// given
+		mock := &countingHttpClient{}
+		wrapped := resilient.WrapHttpClient(mock).WithLimits(resilient.Limits{MaxInFlight: 1})
+		first, err := wrapped.Get("http://example.com/")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&mock.calls))
+
+		// when
+		require.NoError(t, first.Body.Close())
+		second, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, second.StatusCode)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&mock.calls))
+	})
+
+	t.Run("should limit in-flight requests per host", func(t *testing.T) {
+		// given
+		mock := &countingHttpClient{}
+		wrapped := resilient.WrapHttpClient(mock).
+			WithHostLimits(resilient.Limits{MaxInFlight: 1}).
+			WithLimitsForHost("unlimited.example.com", resilient.Limits{})
+		_, err := wrapped.Get("http://example.com/")
+		require.NoError(t, err)
+
+		// when
+		_, otherErr := wrapped.Get("http://other.example.com/")
+		_, unlimitedErr := wrapped.Get("http://unlimited.example.com/")
+		_, unlimitedErr2 := wrapped.Get("http://unlimited.example.com/")
+
+		// then
+		assert.NoError(t, otherErr)
+		assert.NoError(t, unlimitedErr)
+		assert.NoError(t, unlimitedErr2)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&mock.calls))
+	})
+
+	t.Run("should rate limit every attempt", func(t *testing.T) {
+		// given
+		mock := &countingHttpClient{successAfter: 3}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithLimits(resilient.Limits{RequestsPerSecond: 20})
+
+		// when
+		start := time.Now()
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&mock.calls))
+		assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	})
+
+	t.Run("should stop waiting for rate limit when context is done", func(t *testing.T) {
+		// given
+		mock := &countingHttpClient{}
+		wrapped := resilient.WrapHttpClient(mock).WithHostLimits(resilient.Limits{RequestsPerSecond: 0.1})
+		_, err := wrapped.Get("http://example.com/")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		start := time.Now()
+		_, err = wrapped.Do(req)
+
+		// then
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&mock.calls))
+	})
+}
@@ -0,0 +1,125 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	retry "github.com/avast/retry-go"
+	"go.uber.org/zap"
+)
+
+const redactedValue = "xxxxx"
+
+// TracingLogger returns a logger enriched with trace metadata of the context. Logger of the logger package of the
+// common/logging module implements it.
+type TracingLogger interface {
+	WithTracing(ctx context.Context) *zap.SugaredLogger
+}
+
+// WithLogger makes the client log every failed attempt at WARN level and the final failure of a request at ERROR
+// level. Trace metadata is taken from the request context by the logger.
+func (c *WrappedHttpClient) WithLogger(log TracingLogger) *WrappedHttpClient {
+	return c.WithObserver(&loggingObserver{log: log})
+}
+
+// loggingObserver is an Observer which logs failures with TracingLogger
+type loggingObserver struct {
+	log TracingLogger
+}
+
+func (o *loggingObserver) ObserveAttempt(attempt Attempt) {
+	if attempt.Err == nil {
+		return
+	}
+	req := attempt.Request
+	o.log.WithTracing(req.Context()).Warnw("HTTP request attempt failed",
+		"host", req.URL.Host,
+		"method", req.Method,
+		"url", redactURL(req.URL),
+		"attempt", attempt.Number,
+		"statusCode", attempt.StatusCode,
+		"error", redactError(attempt.Err),
+	)
+}
+
+func (o *loggingObserver) ObserveResult(result Result) {
+	if !result.GaveUp {
+		return
+	}
+	req := result.Request
+	keysAndValues := []interface{}{
+		"host", req.URL.Host,
+		"method", req.Method,
+		"url", redactURL(req.URL),
+		"attempts", result.Attempts,
+		"statusCode", result.StatusCode,
+	}
+	if result.Err != nil {
+		keysAndValues = append(keysAndValues, "error", redactError(result.Err))
+	}
+	if result.RetryRefused {
+		keysAndValues = append(keysAndValues, "retryRefused", true)
+	}
+	o.log.WithTracing(req.Context()).Errorw("HTTP request failed", keysAndValues...)
+}
+
+// redactURL returns the URL with the password and values of query parameters replaced, as they may carry secrets
+func redactURL(u *url.URL) string {
+	redacted := *u
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			redacted.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{redactedValue}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+// redactRawURL redacts the URL like redactURL. A URL which can't be parsed is replaced as a whole.
+func redactRawURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedValue
+	}
+	return redactURL(u)
+}
+
+// redactError returns the message of the error with URLs of url.Error redacted, as http.Client puts the whole request
+// URL, including the query, in its errors
+func redactError(err error) string {
+	message := err.Error()
+	for _, urlErr := range urlErrors(err) {
+		redacted := *urlErr
+		redacted.URL = redactRawURL(urlErr.URL)
+		message = strings.ReplaceAll(message, urlErr.Error(), redacted.Error())
+		message = strings.ReplaceAll(message, urlErr.URL, redacted.URL)
+	}
+	return message
+}
+
+// urlErrors returns url.Error of the error chain, including errors of all attempts aggregated by retry-go
+func urlErrors(err error) []*url.Error {
+	var attemptErrs retry.Error
+	if errors.As(err, &attemptErrs) {
+		var urlErrs []*url.Error
+		for _, attemptErr := range attemptErrs {
+			if attemptErr != nil {
+				urlErrs = append(urlErrs, urlErrors(attemptErr)...)
+			}
+		}
+		return urlErrs
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return []*url.Error{urlErr}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package resilient
+
+import (
+	"net/http"
+	"time"
+)
+
+// Attempt describes a single finished attempt of a request
+type Attempt struct {
+	Request *http.Request
+	// Number of the attempt, starting from 1
+	Number int
+	// Delay between the end of the previous attempt and the start of this one. It is zero for the first attempt.
+	Delay time.Duration
+	// Duration of the attempt until the response headers were received
+	Duration time.Duration
+	// StatusCode of the response or 0 if no response was received
+	StatusCode int
+	// Err of the attempt. Responses with retryable status codes are reported with *StatusError.
+	Err error
+}
+
+// Result describes the outcome of a request after all attempts
+type Result struct {
+	Request *http.Request
+	// Attempts made to send the request
+	Attempts int
+	// Duration of all attempts including delays between them
+	Duration time.Duration
+	// StatusCode of the returned response or 0 if no response is returned
+	StatusCode int
+	// Err returned to the caller
+	Err error
+	// GaveUp is true if the request didn't succeed, either with an error or a retryable status code
+	GaveUp bool
+	// RetryRefused is true if a retry was refused by the retry budget, see WithRetryBudget
+	RetryRefused bool
+}
+
+// Observer is notified about attempts and results of requests sent by WrappedHttpClient. Its methods are called
+// synchronously, so they must not block.
+type Observer interface {
+	ObserveAttempt(attempt Attempt)
+	ObserveResult(result Result)
+}
+
+// WithObserver adds an observer notified about every attempt and result. It can be called multiple times.
+func (c *WrappedHttpClient) WithObserver(observer Observer) *WrappedHttpClient {
+	c.observers = append(c.observers, observer)
+	return c
+}
+
+func (c *WrappedHttpClient) observeAttempt(attempt Attempt) {
+	for _, observer := range c.observers {
+		observer.ObserveAttempt(attempt)
+	}
+}
+
+func (c *WrappedHttpClient) observeResult(result Result) {
+	for _, observer := range c.observers {
+		observer.ObserveResult(result)
+	}
+}
+
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
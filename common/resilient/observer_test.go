@@ -0,0 +1,106 @@
+package resilient_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	attempts []resilient.Attempt
+	results  []resilient.Result
+}
+
+func (o *recordingObserver) ObserveAttempt(attempt resilient.Attempt) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.attempts = append(o.attempts, attempt)
+}
+
+func (o *recordingObserver) ObserveResult(result resilient.Result) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.results = append(o.results, result)
+}
+
+func TestHttpClientObserver(t *testing.T) {
+	t.Run("should observe every attempt and the result", func(t *testing.T) {
+		// given
+		mock := &mockHttpClient{successAfter: 3}
+		observer := &recordingObserver{}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(10 * time.Millisecond)).
+			WithObserver(observer)
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, observer.attempts, 3)
+		for i, attempt := range observer.attempts {
+			assert.Equal(t, i+1, attempt.Number)
+			assert.Equal(t, "example.com", attempt.Request.URL.Host)
+		}
+		assert.Zero(t, observer.attempts[0].Delay)
+		assert.GreaterOrEqual(t, observer.attempts[1].Delay, 10*time.Millisecond)
+		assert.Error(t, observer.attempts[0].Err)
+		assert.Zero(t, observer.attempts[0].StatusCode)
+		assert.NoError(t, observer.attempts[2].Err)
+		assert.Equal(t, http.StatusTeapot, observer.attempts[2].StatusCode)
+
+		require.Len(t, observer.results, 1)
+		result := observer.results[0]
+		assert.Equal(t, 3, result.Attempts)
+		assert.Equal(t, http.StatusTeapot, result.StatusCode)
+		assert.False(t, result.GaveUp)
+		assert.GreaterOrEqual(t, result.Duration, 20*time.Millisecond)
+	})
+
+	t.Run("should observe giving up on retryable status code", func(t *testing.T) {
+		// given
+		mock := &statusHttpClient{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+		observer := &recordingObserver{}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(2)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableStatus(resilient.DefaultRetryableStatus).
+			WithObserver(observer)
+
+		// when
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Len(t, observer.attempts, 2)
+		var statusErr *resilient.StatusError
+		assert.ErrorAs(t, observer.attempts[1].Err, &statusErr)
+		require.Len(t, observer.results, 1)
+		assert.True(t, observer.results[0].GaveUp)
+		assert.Equal(t, http.StatusServiceUnavailable, observer.results[0].StatusCode)
+	})
+
+	t.Run("should observe giving up on error", func(t *testing.T) {
+		// given
+		mock := &mockHttpClient{successAfter: 10}
+		observer := &recordingObserver{}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(2)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithObserver(observer)
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.Error(t, err)
+		assert.Len(t, observer.attempts, 2)
+		require.Len(t, observer.results, 1)
+		assert.True(t, observer.results[0].GaveUp)
+		assert.Equal(t, err, observer.results[0].Err)
+	})
+}
@@ -0,0 +1,115 @@
+package resilient
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const prometheusSubsystem = "http_client"
+
+// PrometheusObserver is an Observer which exposes metrics of attempts, retries, give-ups, retries refused by the retry
+// budget and latencies per host and method. It implements prometheus.Collector, so it has to be registered, e.g. with
+// prometheus.MustRegister.
+type PrometheusObserver struct {
+	attempts        *prometheus.CounterVec
+	retries         *prometheus.CounterVec
+	giveUps         *prometheus.CounterVec
+	refusedRetries  *prometheus.CounterVec
+	attemptDuration *prometheus.HistogramVec
+	requestDuration *prometheus.HistogramVec
+}
+
+var _ Observer = &PrometheusObserver{}
+var _ prometheus.Collector = &PrometheusObserver{}
+
+// NewPrometheusObserver returns new PrometheusObserver with metrics in given namespace, which may be empty
+func NewPrometheusObserver(namespace string) *PrometheusObserver {
+	return &PrometheusObserver{
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "attempts_total",
+			Help:      "Number of sent attempts by status code, which is set to \"error\" if no response was received.",
+		}, []string{"host", "method", "code"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "retries_total",
+			Help:      "Number of attempts sent after the first one.",
+		}, []string{"host", "method"}),
+		giveUps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "give_ups_total",
+			Help:      "Number of requests which didn't succeed after all attempts.",
+		}, []string{"host", "method"}),
+		refusedRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "refused_retries_total",
+			Help:      "Number of retries refused by the retry budget.",
+		}, []string{"host", "method"}),
+		attemptDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "attempt_duration_seconds",
+			Help:      "Duration of single attempts.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host", "method"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of requests including all attempts and delays between them.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host", "method"}),
+	}
+}
+
+// ObserveAttempt implements Observer
+func (o *PrometheusObserver) ObserveAttempt(attempt Attempt) {
+	host, method := attempt.Request.URL.Host, attempt.Request.Method
+	code := "error"
+	if attempt.StatusCode != 0 {
+		code = strconv.Itoa(attempt.StatusCode)
+	}
+
+	o.attempts.WithLabelValues(host, method, code).Inc()
+	if attempt.Number > 1 {
+		o.retries.WithLabelValues(host, method).Inc()
+	}
+	o.attemptDuration.WithLabelValues(host, method).Observe(attempt.Duration.Seconds())
+}
+
+// ObserveResult implements Observer
+func (o *PrometheusObserver) ObserveResult(result Result) {
+	host, method := result.Request.URL.Host, result.Request.Method
+	if result.GaveUp {
+		o.giveUps.WithLabelValues(host, method).Inc()
+	}
+	if result.RetryRefused {
+		o.refusedRetries.WithLabelValues(host, method).Inc()
+	}
+	o.requestDuration.WithLabelValues(host, method).Observe(result.Duration.Seconds())
+}
+
+// Describe implements prometheus.Collector
+func (o *PrometheusObserver) Describe(ch chan<- *prometheus.Desc) {
+	o.attempts.Describe(ch)
+	o.retries.Describe(ch)
+	o.giveUps.Describe(ch)
+	o.refusedRetries.Describe(ch)
+	o.attemptDuration.Describe(ch)
+	o.requestDuration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (o *PrometheusObserver) Collect(ch chan<- prometheus.Metric) {
+	o.attempts.Collect(ch)
+	o.retries.Collect(ch)
+	o.giveUps.Collect(ch)
+	o.refusedRetries.Collect(ch)
+	o.attemptDuration.Collect(ch)
+	o.requestDuration.Collect(ch)
+}
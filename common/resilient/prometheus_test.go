@@ -0,0 +1,68 @@
+package resilient_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusObserver(t *testing.T) {
+	// given
+	observer := resilient.NewPrometheusObserver("test")
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(observer))
+
+	succeeding := resilient.WrapHttpClient(&mockHttpClient{successAfter: 3}, retry.Attempts(5)).
+		WithBackoff(fixedBackoff(time.Millisecond)).
+		WithObserver(observer)
+	failing := resilient.WrapHttpClient(&mockHttpClient{successAfter: 10}, retry.Attempts(2)).
+		WithBackoff(fixedBackoff(time.Millisecond)).
+		WithObserver(observer)
+
+	// when
+	_, err := succeeding.Get("http://example.com/")
+	require.NoError(t, err)
+	_, err = failing.Head("http://other.example.com/")
+	require.Error(t, err)
+
+	// then
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.ElementsMatch(t, []string{
+		"test_http_client_attempts_total",
+		"test_http_client_retries_total",
+		"test_http_client_give_ups_total",
+		"test_http_client_attempt_duration_seconds",
+		"test_http_client_request_duration_seconds",
+	}, names)
+
+	expected := `
+# HELP test_http_client_attempts_total Number of sent attempts by status code, which is set to "error" if no response was received.
+# TYPE test_http_client_attempts_total counter
+test_http_client_attempts_total{code="418",host="example.com",method="GET"} 1
+test_http_client_attempts_total{code="error",host="example.com",method="GET"} 2
+test_http_client_attempts_total{code="error",host="other.example.com",method="HEAD"} 2
+# HELP test_http_client_give_ups_total Number of requests which didn't succeed after all attempts.
+# TYPE test_http_client_give_ups_total counter
+test_http_client_give_ups_total{host="other.example.com",method="HEAD"} 1
+# HELP test_http_client_retries_total Number of attempts sent after the first one.
+# TYPE test_http_client_retries_total counter
+test_http_client_retries_total{host="example.com",method="GET"} 2
+test_http_client_retries_total{host="other.example.com",method="HEAD"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"test_http_client_attempts_total", "test_http_client_give_ups_total", "test_http_client_retries_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(observer, "test_http_client_request_duration_seconds"))
+}
@@ -0,0 +1,54 @@
+package resilient
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxRetryAfter is the default limit of a delay requested by the Retry-After header
+const DefaultMaxRetryAfter = 30 * time.Second
+
+const retryAfterHeader = "Retry-After"
+
+// WithMaxRetryAfter sets the limit of a delay requested by the Retry-After header of a retryable response. The delay
+// before the next attempt is the longer of the Retry-After delay and the delay computed by the backoff.
+func (c *WrappedHttpClient) WithMaxRetryAfter(max time.Duration) *WrappedHttpClient {
+	c.maxRetryAfter = max
+	return c
+}
+
+// retryAfter returns the delay requested by the response, capped by the configured maximum
+func (c *WrappedHttpClient) retryAfter(resp *http.Response, now time.Time) time.Duration {
+	delay, ok := parseRetryAfter(resp.Header.Get(retryAfterHeader), now)
+	if !ok {
+		return 0
+	}
+	if delay > c.maxRetryAfter {
+		return c.maxRetryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter parses both forms of the Retry-After header: delay in seconds and HTTP-date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
@@ -0,0 +1,102 @@
+package resilient_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type retryAfterHttpClient struct {
+	retryAfter string
+	calls      []time.Time
+}
+
+func (c *retryAfterHttpClient) Do(req *http.Request) (*http.Response, error) {
+	c.calls = append(c.calls, time.Now())
+	if len(c.calls) > 1 {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": {c.retryAfter}},
+	}, nil
+}
+
+func TestHttpClientRetryAfter(t *testing.T) {
+	testCases := []struct {
+		name          string
+		retryAfter    string
+		maxRetryAfter time.Duration
+		minDelay      time.Duration
+		maxDelay      time.Duration
+	}{
+		{
+			name:          "delay in seconds capped by maximum",
+			retryAfter:    "10",
+			maxRetryAfter: 50 * time.Millisecond,
+			minDelay:      50 * time.Millisecond,
+			maxDelay:      time.Second,
+		},
+		{
+			name:          "HTTP-date capped by maximum",
+			retryAfter:    time.Now().Add(time.Hour).UTC().Format(http.TimeFormat),
+			maxRetryAfter: 50 * time.Millisecond,
+			minDelay:      50 * time.Millisecond,
+			maxDelay:      time.Second,
+		},
+		{
+			name:          "HTTP-date in the past",
+			retryAfter:    time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+			maxRetryAfter: time.Minute,
+			maxDelay:      50 * time.Millisecond,
+		},
+		{
+			name:          "invalid value",
+			retryAfter:    "soon",
+			maxRetryAfter: time.Minute,
+			maxDelay:      50 * time.Millisecond,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// given
+			mock := &retryAfterHttpClient{retryAfter: testCase.retryAfter}
+			wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+				WithRetryableStatus(resilient.DefaultRetryableStatus).
+				WithMaxRetryAfter(testCase.maxRetryAfter)
+
+			// when
+			resp, err := wrapped.Get("http://example.com/")
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Len(t, mock.calls, 2)
+			delay := mock.calls[1].Sub(mock.calls[0])
+			assert.GreaterOrEqual(t, delay, testCase.minDelay)
+			assert.Less(t, delay, testCase.maxDelay)
+		})
+	}
+
+	t.Run("should not shorten delay computed from retry options", func(t *testing.T) {
+		// given
+		mock := &retryAfterHttpClient{retryAfter: "0"}
+		wrapped := resilient.WrapHttpClient(mock).WithBackoff(fixedBackoff(50 * time.Millisecond)).
+			WithRetryableStatus(resilient.DefaultRetryableStatus)
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, mock.calls, 2)
+		assert.GreaterOrEqual(t, mock.calls[1].Sub(mock.calls[0]), 50*time.Millisecond)
+	})
+}
@@ -0,0 +1,85 @@
+package resilient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxDrainBytes limits how much of a discarded response body is read, so that the connection can be reused
+const maxDrainBytes = 4 << 10
+
+// RetryableStatusFunc decides whether a response with given status code should be retried
+type RetryableStatusFunc func(statusCode int) bool
+
+// StatusError is returned from an attempt which ended with a retryable status code
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the delay requested by the Retry-After header, capped by WithMaxRetryAfter
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("retryable status code: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// DefaultRetryableStatus treats 408, 429 and 5xx responses as retryable, except 501 and 505 which will never succeed
+func DefaultRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	default:
+		return statusCode >= 500 && statusCode <= 599
+	}
+}
+
+// RetryOnStatusCodes returns RetryableStatusFunc which treats only given status codes as retryable
+func RetryOnStatusCodes(codes ...int) RetryableStatusFunc {
+	retryable := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		retryable[code] = true
+	}
+	return func(statusCode int) bool {
+		return retryable[statusCode]
+	}
+}
+
+// WithRetryableStatus makes the client retry requests answered with a retryable status code, e.g.
+// WithRetryableStatus(DefaultRetryableStatus). Bodies of discarded responses are drained and closed. When attempts are
+// exhausted, the last response is returned if the last attempt received one, otherwise the error is returned.
+func (c *WrappedHttpClient) WithRetryableStatus(retryable RetryableStatusFunc) *WrappedHttpClient {
+	c.retryableStatus = retryable
+	return c
+}
+
+// checkStatus returns StatusError if the response should be retried
+func (c *WrappedHttpClient) checkStatus(resp *http.Response) *StatusError {
+	if c.retryableStatus == nil || resp == nil || !c.retryableStatus(resp.StatusCode) {
+		return nil
+	}
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: c.retryAfter(resp, time.Now()),
+	}
+}
+
+// isFailureStatus returns true if the status code means that the host failed to handle the request. It uses the
+// policy configured with WithRetryableStatus and falls back to DefaultRetryableStatus.
+func (c *WrappedHttpClient) isFailureStatus(statusCode int) bool {
+	if c.retryableStatus != nil {
+		return c.retryableStatus(statusCode)
+	}
+	return DefaultRetryableStatus(statusCode)
+}
+
+// discardResponse drains and closes the body of a response which won't be returned to the caller
+func discardResponse(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,148 @@
+package resilient_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+type statusHttpClient struct {
+	statuses []int
+	bodies   []*trackingBody
+}
+
+func (c *statusHttpClient) Do(req *http.Request) (*http.Response, error) {
+	status := c.statuses[0]
+	if len(c.statuses) > 1 {
+		c.statuses = c.statuses[1:]
+	}
+	if status == 0 {
+		return nil, errors.New("some connection error")
+	}
+	body := &trackingBody{Reader: strings.NewReader(http.StatusText(status))}
+	c.bodies = append(c.bodies, body)
+	return &http.Response{StatusCode: status, Body: body}, nil
+}
+
+func TestHttpClientRetryableStatus(t *testing.T) {
+	t.Run("should retry retryable status codes until success", func(t *testing.T) {
+		// given
+		mock := &statusHttpClient{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableStatus(resilient.DefaultRetryableStatus)
+
+		// when
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Len(t, mock.bodies, 3)
+		assert.True(t, mock.bodies[0].closed)
+		assert.True(t, mock.bodies[1].closed)
+		assert.False(t, mock.bodies[2].closed)
+	})
+
+	t.Run("should return last response when attempts are exhausted", func(t *testing.T) {
+		// given
+		mock := &statusHttpClient{statuses: []int{http.StatusBadGateway}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableStatus(resilient.DefaultRetryableStatus)
+
+		// when
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		require.Len(t, mock.bodies, 3)
+		assert.False(t, mock.bodies[2].closed)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "Bad Gateway", string(body))
+	})
+
+	t.Run("should return typed error when last attempt failed", func(t *testing.T) {
+		// given
+		mock := &statusHttpClient{statuses: []int{http.StatusServiceUnavailable, 0}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableStatus(resilient.DefaultRetryableStatus)
+
+		// when
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.Error(t, err)
+		assert.Nil(t, resp)
+		retryErr, ok := err.(retry.Error)
+		require.True(t, ok)
+		statusErr, ok := retryErr[0].(*resilient.StatusError)
+		require.True(t, ok)
+		assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+		assert.True(t, mock.bodies[0].closed)
+	})
+
+	t.Run("should not retry status codes without policy", func(t *testing.T) {
+		// given
+		mock := &statusHttpClient{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond))
+
+		// when
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Len(t, mock.bodies, 1)
+	})
+
+	t.Run("should retry only selected status codes", func(t *testing.T) {
+		// given
+		mock := &statusHttpClient{statuses: []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(3)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithRetryableStatus(resilient.RetryOnStatusCodes(http.StatusServiceUnavailable))
+
+		// when
+		resp, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Len(t, mock.bodies, 2)
+	})
+}
+
+func TestDefaultRetryableStatus(t *testing.T) {
+	for code, expected := range map[int]bool{
+		http.StatusOK:                      false,
+		http.StatusBadRequest:              false,
+		http.StatusRequestTimeout:          true,
+		http.StatusTooManyRequests:         true,
+		http.StatusInternalServerError:     true,
+		http.StatusNotImplemented:          false,
+		http.StatusServiceUnavailable:      true,
+		http.StatusGatewayTimeout:          true,
+		http.StatusHTTPVersionNotSupported: false,
+	} {
+		assert.Equal(t, expected, resilient.DefaultRetryableStatus(code), "status code %d", code)
+	}
+}
@@ -0,0 +1,201 @@
+package resilient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Tracing headers injected into outbound requests
+const (
+	traceHeader       = "X-B3-TraceId"
+	spanHeader        = "X-B3-SpanId"
+	parentSpanHeader  = "X-B3-ParentSpanId"
+	sampledHeader     = "X-B3-Sampled"
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+)
+
+// TracePropagation selects headers used to propagate trace metadata to outbound requests. Values can be combined,
+// e.g. B3Propagation | W3CPropagation
+type TracePropagation int
+
+const (
+	// B3Propagation injects X-B3-* headers
+	B3Propagation TracePropagation = 1 << iota
+	// W3CPropagation injects traceparent and tracestate headers
+	W3CPropagation
+)
+
+const (
+	w3cTraceIDLength = 32
+	w3cSpanIDLength  = 16
+)
+
+// TraceMetadata is trace metadata of a request propagated to outbound requests. Trace of the tracing package of the
+// common/logging module implements it.
+type TraceMetadata interface {
+	TraceID() string
+	SpanID() string
+	ParentSpanID() string
+	// Sampled returns the sampling decision. The second return value is false if the decision is unknown.
+	Sampled() (bool, bool)
+	TraceState() string
+}
+
+// TraceFromContextFunc returns trace metadata stored in the context. The second return value is false if there is
+// none.
+type TraceFromContextFunc func(ctx context.Context) (TraceMetadata, bool)
+
+// TraceFrom adapts a function returning a concrete implementation of TraceMetadata, e.g. FromContext of the tracing
+// package of the common/logging module, to TraceFromContextFunc
+func TraceFrom[T TraceMetadata](fromContext func(ctx context.Context) (T, bool)) TraceFromContextFunc {
+	return func(ctx context.Context) (TraceMetadata, bool) {
+		return fromContext(ctx)
+	}
+}
+
+// WithTracePropagation makes the client inject trace metadata of the request context into every outbound request.
+// Headers already set on the request are not overridden. Metadata is read from the OpenTelemetry span of the context,
+// unless configured otherwise with WithTraceFromContext.
+func (c *WrappedHttpClient) WithTracePropagation(propagation TracePropagation) *WrappedHttpClient {
+	c.tracePropagation = propagation
+	return c
+}
+
+// WithTraceFromContext sets the function reading trace metadata propagated with WithTracePropagation, e.g.
+// TraceFrom(tracing.FromContext) to propagate metadata stored by tracing.NewTracingMiddleware of the common/logging
+// module.
+func (c *WrappedHttpClient) WithTraceFromContext(fromContext TraceFromContextFunc) *WrappedHttpClient {
+	c.traceFromContext = fromContext
+	return c
+}
+
+// otelTrace is TraceMetadata of an OpenTelemetry span
+type otelTrace struct {
+	oteltrace.SpanContext
+}
+
+func (t otelTrace) TraceID() string {
+	return t.SpanContext.TraceID().String()
+}
+
+func (t otelTrace) SpanID() string {
+	return t.SpanContext.SpanID().String()
+}
+
+func (t otelTrace) ParentSpanID() string {
+	return ""
+}
+
+func (t otelTrace) Sampled() (bool, bool) {
+	return t.IsSampled(), true
+}
+
+func (t otelTrace) TraceState() string {
+	return t.SpanContext.TraceState().String()
+}
+
+// traceFromOpenTelemetry returns metadata of the OpenTelemetry span stored in the context
+func traceFromOpenTelemetry(ctx context.Context) (TraceMetadata, bool) {
+	spanContext := oteltrace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil, false
+	}
+	return otelTrace{SpanContext: spanContext}, true
+}
+
+// injectTraceHeaders returns a copy of the request enriched with tracing headers. The original request is returned if
+// there is nothing to inject.
+func (c *WrappedHttpClient) injectTraceHeaders(req *http.Request) *http.Request {
+	if c.tracePropagation == 0 {
+		return req
+	}
+	fromContext := c.traceFromContext
+	if fromContext == nil {
+		fromContext = traceFromOpenTelemetry
+	}
+	headers := traceHeaders(req.Context(), c.tracePropagation, fromContext)
+	if len(headers) == 0 {
+		return req
+	}
+
+	out := req.Clone(req.Context())
+	for key, values := range headers {
+		if _, ok := out.Header[key]; ok {
+			continue
+		}
+		out.Header[key] = values
+	}
+	return out
+}
+
+func traceHeaders(ctx context.Context, propagation TracePropagation, fromContext TraceFromContextFunc) http.Header {
+	headers := http.Header{}
+	trace, ok := fromContext(ctx)
+	if !ok || trace.TraceID() == "" || trace.SpanID() == "" {
+		return headers
+	}
+	sampled, hasSampled := trace.Sampled()
+
+	if propagation&B3Propagation != 0 {
+		headers.Set(traceHeader, trace.TraceID())
+		headers.Set(spanHeader, trace.SpanID())
+		if trace.ParentSpanID() != "" {
+			headers.Set(parentSpanHeader, trace.ParentSpanID())
+		}
+		if hasSampled {
+			headers.Set(sampledHeader, b3Sampled(sampled))
+		}
+	}
+
+	if propagation&W3CPropagation != 0 {
+		traceParent, ok := formatTraceParent(trace.TraceID(), trace.SpanID(), sampled)
+		if !ok {
+			return headers
+		}
+		headers.Set(traceParentHeader, traceParent)
+		if trace.TraceState() != "" {
+			headers.Set(traceStateHeader, trace.TraceState())
+		}
+	}
+	return headers
+}
+
+func b3Sampled(sampled bool) string {
+	if sampled {
+		return "1"
+	}
+	return "0"
+}
+
+// formatTraceParent builds the W3C traceparent header. B3 allows 64-bit trace IDs, so they are left-padded with zeros.
+// It returns false if IDs can't be represented in the W3C format.
+func formatTraceParent(traceID, spanID string, sampled bool) (string, bool) {
+	if len(traceID) < w3cTraceIDLength {
+		traceID = strings.Repeat("0", w3cTraceIDLength-len(traceID)) + traceID
+	}
+	if !isW3CID(traceID, w3cTraceIDLength) || !isW3CID(spanID, w3cSpanIDLength) {
+		return "", false
+	}
+	flags := "00"
+	if sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", traceID, spanID, flags), true
+}
+
+func isW3CID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
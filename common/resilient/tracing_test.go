@@ -0,0 +1,198 @@
+package resilient_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+type recordingHttpClient struct {
+	mockHttpClient
+	requests []*http.Request
+}
+
+func (c *recordingHttpClient) Do(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req)
+	return c.mockHttpClient.Do(req)
+}
+
+func TestHttpClientTracePropagation(t *testing.T) {
+	t.Run("should inject B3 headers on every attempt", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 3}}
+		wrapped := resilient.WrapHttpClient(mock, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+			WithTracePropagation(resilient.B3Propagation).
+			WithTraceFromContext(resilient.TraceFrom(traceFromContext))
+		ctx := fixTracingContext(testTrace{
+			traceID:      "80f198ee56343ba864fe8b2a57d3eff7",
+			spanID:       "e457b5a2e4d86bd1",
+			parentSpanID: "05e3ac9a4f6e3b90",
+			sampled:      true,
+			sampledKnown: true,
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, mock.requests, 3)
+		for _, sent := range mock.requests {
+			assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", sent.Header.Get("X-B3-TraceId"))
+			assert.Equal(t, "e457b5a2e4d86bd1", sent.Header.Get("X-B3-SpanId"))
+			assert.Equal(t, "05e3ac9a4f6e3b90", sent.Header.Get("X-B3-ParentSpanId"))
+			assert.Equal(t, "1", sent.Header.Get("X-B3-Sampled"))
+			assert.Empty(t, sent.Header.Get("traceparent"))
+		}
+		assert.Empty(t, req.Header, "original request should not be modified")
+	})
+
+	t.Run("should inject W3C headers", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 1}}
+		wrapped := resilient.WrapHttpClient(mock).
+			WithTracePropagation(resilient.W3CPropagation).
+			WithTraceFromContext(resilient.TraceFrom(traceFromContext))
+		ctx := fixTracingContext(testTrace{
+			traceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+			spanID:       "00f067aa0ba902b7",
+			sampled:      true,
+			sampledKnown: true,
+			traceState:   "rojo=00f067aa0ba902b7",
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, mock.requests, 1)
+		sent := mock.requests[0]
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sent.Header.Get("traceparent"))
+		assert.Equal(t, "rojo=00f067aa0ba902b7", sent.Header.Get("tracestate"))
+		assert.Empty(t, sent.Header.Get("X-B3-TraceId"))
+	})
+
+	t.Run("should pad 64-bit B3 trace id for W3C headers", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 1}}
+		wrapped := resilient.WrapHttpClient(mock).
+			WithTracePropagation(resilient.B3Propagation | resilient.W3CPropagation).
+			WithTraceFromContext(resilient.TraceFrom(traceFromContext))
+		ctx := fixTracingContext(testTrace{traceID: "64fe8b2a57d3eff7", spanID: "e457b5a2e4d86bd1", sampledKnown: true})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		sent := mock.requests[0]
+		assert.Equal(t, "00-000000000000000064fe8b2a57d3eff7-e457b5a2e4d86bd1-00", sent.Header.Get("traceparent"))
+		assert.Equal(t, "64fe8b2a57d3eff7", sent.Header.Get("X-B3-TraceId"))
+		assert.Equal(t, "0", sent.Header.Get("X-B3-Sampled"))
+	})
+
+	t.Run("should not override headers set by the caller", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 1}}
+		wrapped := resilient.WrapHttpClient(mock).
+			WithTracePropagation(resilient.B3Propagation).
+			WithTraceFromContext(resilient.TraceFrom(traceFromContext))
+		ctx := fixTracingContext(testTrace{traceID: "mytrace", spanID: "myspan"})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-B3-SpanId", "otherspan")
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		sent := mock.requests[0]
+		assert.Equal(t, "mytrace", sent.Header.Get("X-B3-TraceId"))
+		assert.Equal(t, "otherspan", sent.Header.Get("X-B3-SpanId"))
+	})
+
+	t.Run("should inject headers of OpenTelemetry span by default", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 1}}
+		wrapped := resilient.WrapHttpClient(mock).WithTracePropagation(resilient.B3Propagation | resilient.W3CPropagation)
+		traceID, err := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		require.NoError(t, err)
+		spanID, err := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+		require.NoError(t, err)
+		ctx := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: oteltrace.FlagsSampled,
+		}))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		_, err = wrapped.Do(req)
+
+		// then
+		require.NoError(t, err)
+		sent := mock.requests[0]
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sent.Header.Get("traceparent"))
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sent.Header.Get("X-B3-TraceId"))
+		assert.Equal(t, "00f067aa0ba902b7", sent.Header.Get("X-B3-SpanId"))
+		assert.Equal(t, "1", sent.Header.Get("X-B3-Sampled"))
+	})
+
+	t.Run("should not inject anything without trace metadata", func(t *testing.T) {
+		// given
+		mock := &recordingHttpClient{mockHttpClient: mockHttpClient{successAfter: 1}}
+		wrapped := resilient.WrapHttpClient(mock).WithTracePropagation(resilient.B3Propagation | resilient.W3CPropagation)
+
+		// when
+		_, err := wrapped.Get("http://example.com/")
+
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, mock.requests[0].Header)
+	})
+}
+
+// testTrace is TraceMetadata stored in the context by fixTracingContext, like Trace of the tracing package of the
+// common/logging module
+type testTrace struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	sampled      bool
+	sampledKnown bool
+	traceState   string
+}
+
+func (t testTrace) TraceID() string       { return t.traceID }
+func (t testTrace) SpanID() string        { return t.spanID }
+func (t testTrace) ParentSpanID() string  { return t.parentSpanID }
+func (t testTrace) Sampled() (bool, bool) { return t.sampled, t.sampledKnown }
+func (t testTrace) TraceState() string    { return t.traceState }
+
+type testTraceKey struct{}
+
+func fixTracingContext(trace testTrace) context.Context {
+	return context.WithValue(context.Background(), testTraceKey{}, trace)
+}
+
+func traceFromContext(ctx context.Context) (testTrace, bool) {
+	trace, ok := ctx.Value(testTraceKey{}).(testTrace)
+	return trace, ok
+}
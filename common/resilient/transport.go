@@ -0,0 +1,53 @@
+package resilient
+
+import (
+	"net/http"
+
+	retry "github.com/avast/retry-go"
+)
+
+var _ http.RoundTripper = &WrappedHttpClient{}
+
+// WrapTransport returns new WrappedHttpClient which sends requests with given transport. It implements
+// http.RoundTripper, so it can be used as http.Client.Transport. http.DefaultTransport is used if transport is nil.
+func WrapTransport(transport http.RoundTripper, opts ...retry.Option) *WrappedHttpClient {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return WrapHttpClient(transportClient{transport: transport}, opts...)
+}
+
+// TransportWrapper returns a function which wraps transports with WrappedHttpClient, e.g. for rest.Config.WrapTransport.
+// The configure function, which may be nil, is called for every wrapped transport to set up the client, e.g.
+//
+//	config.WrapTransport = resilient.TransportWrapper(func(c *resilient.WrappedHttpClient) *resilient.WrappedHttpClient {
+//		return c.WithRetryableStatus(resilient.DefaultRetryableStatus)
+//	}, retry.Attempts(3))
+func TransportWrapper(configure func(*WrappedHttpClient) *WrappedHttpClient, opts ...retry.Option) func(http.RoundTripper) http.RoundTripper {
+	return func(transport http.RoundTripper) http.RoundTripper {
+		client := WrapTransport(transport, opts...)
+		if configure != nil {
+			client = configure(client)
+		}
+		return client
+	}
+}
+
+// RoundTrip implements http.RoundTripper with the same semantics as Do. The request is not modified, as every attempt
+// is sent with its copy. Like http.Client, it closes the request body when an error is returned.
+func (c *WrappedHttpClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.Do(req)
+	if err != nil && req.Body != nil {
+		_ = req.Body.Close()
+	}
+	return resp, err
+}
+
+// transportClient adapts http.RoundTripper to HttpClient
+type transportClient struct {
+	transport http.RoundTripper
+}
+
+func (c transportClient) Do(req *http.Request) (*http.Response, error) {
+	return c.transport.RoundTrip(req)
+}
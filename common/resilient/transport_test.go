@@ -0,0 +1,101 @@
+package resilient_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/kyma/common/resilient"
+
+	retry "github.com/avast/retry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRoundTripper(t *testing.T) {
+	t.Run("should retry requests sent by http.Client", func(t *testing.T) {
+		// given
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "payload", string(body))
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = io.WriteString(w, "ok")
+		}))
+		defer server.Close()
+		client := &http.Client{
+			Transport: resilient.WrapTransport(nil, retry.Attempts(5)).WithBackoff(fixedBackoff(time.Millisecond)).
+				WithRetryableStatus(resilient.DefaultRetryableStatus),
+		}
+		req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("payload"))
+		require.NoError(t, err)
+
+		// when
+		resp, err := client.Do(req)
+
+		// then
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(body))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should wrap transport with configured client", func(t *testing.T) {
+		// given
+		var calls int
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls < 2 {
+				return &http.Response{StatusCode: http.StatusTooManyRequests, Body: http.NoBody}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+		wrap := resilient.TransportWrapper(func(c *resilient.WrappedHttpClient) *resilient.WrappedHttpClient {
+			return c.WithRetryableStatus(resilient.DefaultRetryableStatus).WithBackoff(fixedBackoff(time.Millisecond))
+		}, retry.Attempts(5))
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		resp, err := wrap(transport).RoundTrip(req)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("should close request body on error", func(t *testing.T) {
+		// given
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("some connection error")
+		})
+		body := &trackingBody{Reader: strings.NewReader("payload")}
+		req, err := http.NewRequest(http.MethodPost, "http://example.com/", body)
+		require.NoError(t, err)
+
+		// when
+		_, err = resilient.WrapTransport(transport).RoundTrip(req)
+
+		// then
+		require.Error(t, err)
+		assert.True(t, body.closed)
+	})
+}